@@ -1,8 +1,33 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"os"
 
-// TODO: report proper error
-func reportError(err error) {
-	fmt.Println(err)
+	"github.com/aselhid/indoscript/pkg/indoscript"
+)
+
+// exit codes follow sysexits.h
+const (
+	exitUsage    = 64
+	exitDataErr  = 65
+	exitSoftware = 70
+	exitIOErr    = 74
+)
+
+// reportError renders err with the lines of source it points at.
+func reportError(filename, source string, err error) {
+	indoscript.PrintError(os.Stderr, filename, source, err)
+}
+
+func exitCode(err error) int {
+	var list indoscript.ErrorList
+	if errors.As(err, &list) {
+		return exitDataErr
+	}
+	var scriptErr *indoscript.Error
+	if errors.As(err, &scriptErr) {
+		return exitSoftware
+	}
+	return exitIOErr
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aselhid/indoscript/pkg/indoscript"
+)
+
+// formatFiles implements the fmt subcommand. The formatted source is printed
+// unless -w rewrites the files in place or -d prints what would change, with
+// no files the source is read from in.
+func formatFiles(args []string, in io.Reader, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	diff := flags.Bool("d", false, "print a diff instead of the formatted source")
+	flags.Usage = func() {
+		fmt.Fprintln(errOut, "Usage: indoscript fmt [-w] [-d] [script].indos...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(errOut, "can't use -w on standard input")
+			return exitUsage
+		}
+		source, err := io.ReadAll(in)
+		if err != nil {
+			fmt.Fprintln(errOut, err)
+			return exitIOErr
+		}
+		return formatSource("<stdin>", string(source), *write, *diff, out, errOut)
+	}
+
+	code := 0
+	for _, filename := range flags.Args() {
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(errOut, err)
+			code = exitIOErr
+			continue
+		}
+		if c := formatSource(filename, string(source), *write, *diff, out, errOut); c != 0 {
+			code = c
+		}
+	}
+	return code
+}
+
+func formatSource(filename, source string, write, diff bool, out, errOut io.Writer) int {
+	formatted, err := indoscript.Format(source)
+	if err != nil {
+		indoscript.PrintError(errOut, filename, source, err)
+		return exitCode(err)
+	}
+
+	if diff {
+		io.WriteString(out, unifiedDiff(filename, source, formatted))
+	}
+	if write {
+		if formatted == source {
+			return 0
+		}
+		if err := os.WriteFile(filename, []byte(formatted), 0o644); err != nil {
+			fmt.Fprintln(errOut, err)
+			return exitIOErr
+		}
+	}
+	if !diff && !write {
+		io.WriteString(out, formatted)
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatFiles(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "skrip.indos")
+	if err := os.WriteFile(filename, []byte("misal a=1;\ncetak a;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(strings.Builder)
+	if code := formatFiles([]string{"-d", filename}, nil, out, new(strings.Builder)); code != 0 {
+		t.Fatalf("expected exit code 0 while actual is %d", code)
+	}
+	expectedDiff := "--- " + filename + "\n+++ " + filename + "\n" +
+		"@@ -1,2 +1,2 @@\n-misal a=1;\n+misal a = 1;\n cetak a;\n"
+	if out.String() != expectedDiff {
+		t.Fatalf("expected diff\n%s\nwhile actual is\n%s", expectedDiff, out)
+	}
+
+	if code := formatFiles([]string{"-w", filename}, nil, new(strings.Builder), new(strings.Builder)); code != 0 {
+		t.Fatalf("expected exit code 0 while actual is %d", code)
+	}
+	written, _ := os.ReadFile(filename)
+	if string(written) != "misal a = 1;\ncetak a;\n" {
+		t.Fatalf("unexpected file content %q", written)
+	}
+
+	out.Reset()
+	formatFiles(nil, strings.NewReader("cetak  1;"), out, new(strings.Builder))
+	if out.String() != "cetak 1;\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	errOut := new(strings.Builder)
+	if code := formatFiles(nil, strings.NewReader("cetak ;"), new(strings.Builder), errOut); code != exitDataErr {
+		t.Fatalf("expected exit code %d while actual is %d", exitDataErr, code)
+	}
+	if !strings.Contains(errOut.String(), "expect expression") {
+		t.Fatalf("expected a syntax error while actual is %q", errOut)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	after := "1\n2b\n3\n4\n5\n6\n7\n8\n9\n10\n12\n13\n"
+	expected := "--- f\n+++ f\n" +
+		"@@ -1,5 +1,5 @@\n 1\n-2\n+2b\n 3\n 4\n 5\n" +
+		"@@ -8,5 +8,5 @@\n 8\n 9\n 10\n-11\n 12\n+13\n"
+	if actual := unifiedDiff("f", before, after); actual != expected {
+		t.Fatalf("expected\n%s\nwhile actual is\n%s", expected, actual)
+	}
+	if actual := unifiedDiff("f", before, before); actual != "" {
+		t.Fatalf("expected no diff while actual is\n%s", actual)
+	}
+}
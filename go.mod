@@ -2,4 +2,4 @@ module github.com/aselhid/indoscript
 
 go 1.21.4
 
-require github.com/google/go-cmp v0.6.0 // indirect
+require github.com/google/go-cmp v0.6.0
@@ -1,17 +1,25 @@
 package ast
 
 type ExprVisitor interface {
-	VisitBinaryExpr(expr BinaryExpr) any
-	VisitUnaryExpr(expr UnaryExpr) any
-	VisitPrimaryExpr(expr PrimaryExpr) any
-	VisitGroupExpr(expr GroupExpr) any
-	VisitVarExpr(expr VarExpr) any
-	VisitLogicalExpr(expr LogicalExpr) any
-	VisitCallExpr(expr CallExpr) any
+	VisitBinaryExpr(expr BinaryExpr) (any, error)
+	VisitUnaryExpr(expr UnaryExpr) (any, error)
+	VisitPrimaryExpr(expr PrimaryExpr) (any, error)
+	VisitGroupExpr(expr GroupExpr) (any, error)
+	VisitVarExpr(expr VarExpr) (any, error)
+	VisitLogicalExpr(expr LogicalExpr) (any, error)
+	VisitCallExpr(expr CallExpr) (any, error)
+	VisitListExpr(expr ListExpr) (any, error)
+	VisitIndexExpr(expr IndexExpr) (any, error)
+	VisitMapExpr(expr MapExpr) (any, error)
+	VisitFuncExpr(expr FuncExpr) (any, error)
+	VisitPropertyExpr(expr PropertyExpr) (any, error)
+	VisitThisExpr(expr ThisExpr) (any, error)
+	VisitSuperExpr(expr SuperExpr) (any, error)
+	VisitTemplateExpr(expr TemplateExpr) (any, error)
 }
 
 type Expr interface {
-	Accept(visitor ExprVisitor) any
+	Accept(visitor ExprVisitor) (any, error)
 }
 
 type BinaryExpr struct {
@@ -20,7 +28,7 @@ type BinaryExpr struct {
 	Operator Token
 }
 
-func (e BinaryExpr) Accept(visitor ExprVisitor) any {
+func (e BinaryExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitBinaryExpr(e)
 }
 
@@ -38,7 +46,7 @@ type LogicalExpr struct {
 	Operator Token
 }
 
-func (e LogicalExpr) Accept(visitor ExprVisitor) any {
+func (e LogicalExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitLogicalExpr(e)
 }
 
@@ -55,7 +63,7 @@ type UnaryExpr struct {
 	Operator Token
 }
 
-func (e UnaryExpr) Accept(visitor ExprVisitor) any {
+func (e UnaryExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitUnaryExpr(e)
 }
 
@@ -68,56 +76,220 @@ func NewUnaryExpr(operator Token, right Expr) UnaryExpr {
 
 type PrimaryExpr struct {
 	Literal any
+	// Token is the literal as written in the source
+	Token Token
 }
 
-func (e PrimaryExpr) Accept(visitor ExprVisitor) any {
+func (e PrimaryExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitPrimaryExpr(e)
 }
 
-func NewPrimaryExpr(literal any) PrimaryExpr {
+func NewPrimaryExpr(token Token, literal any) PrimaryExpr {
 	return PrimaryExpr{
 		Literal: literal,
+		Token:   token,
 	}
 }
 
 type GroupExpr struct {
-	expression Expr
+	Expression Expr
+	// Parenthesis is the opening '('
+	Parenthesis Token
 }
 
-func (e GroupExpr) Accept(visitor ExprVisitor) any {
+func (e GroupExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitGroupExpr(e)
 }
 
-func NewGroupExpr(expression Expr) GroupExpr {
-	return GroupExpr{expression: expression}
+func NewGroupExpr(parenthesis Token, expression Expr) GroupExpr {
+	return GroupExpr{Expression: expression, Parenthesis: parenthesis}
+}
+
+// Binding is filled in by the resolver with the number of scopes between a
+// variable reference and the scope that declares it. It is shared by every
+// copy of the node holding it, so a negative Depth means the variable was not
+// found in any enclosing scope and lives in the global environment.
+type Binding struct {
+	Depth int
+}
+
+func NewBinding() *Binding {
+	return &Binding{Depth: -1}
 }
 
 type VarExpr struct {
 	Identifier Token
+	Binding    *Binding
 }
 
-func (e VarExpr) Accept(visitor ExprVisitor) any {
+func (e VarExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitVarExpr(e)
 }
 
 func NewVarExpr(identifier Token) VarExpr {
-	return VarExpr{Identifier: identifier}
+	return VarExpr{Identifier: identifier, Binding: NewBinding()}
 }
 
 type CallExpr struct {
-	Callee      Expr
-	Arguments   []Expr
+	Callee    Expr
+	Arguments []Expr
+	// Names holds the name of every argument given as 'name: value' and the
+	// zero Token for positional arguments, which all come first
+	Names       []Token
 	Parenthesis Token
 }
 
-func (e CallExpr) Accept(visitor ExprVisitor) any {
+func (e CallExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitCallExpr(e)
 }
 
-func NewCallExpr(callee Expr, arguments []Expr, parenthesis Token) CallExpr {
+func NewCallExpr(callee Expr, arguments []Expr, names []Token, parenthesis Token) CallExpr {
 	return CallExpr{
 		Callee:      callee,
 		Arguments:   arguments,
+		Names:       names,
 		Parenthesis: parenthesis,
 	}
 }
+
+type ListExpr struct {
+	Elements []Expr
+	// Bracket is the opening '['
+	Bracket Token
+}
+
+func (e ListExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitListExpr(e)
+}
+
+func NewListExpr(elements []Expr, bracket Token) ListExpr {
+	return ListExpr{
+		Elements: elements,
+		Bracket:  bracket,
+	}
+}
+
+type IndexExpr struct {
+	Object  Expr
+	Index   Expr
+	Bracket Token
+}
+
+func (e IndexExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitIndexExpr(e)
+}
+
+func NewIndexExpr(object Expr, index Expr, bracket Token) IndexExpr {
+	return IndexExpr{
+		Object:  object,
+		Index:   index,
+		Bracket: bracket,
+	}
+}
+
+type MapExpr struct {
+	Keys   []Expr
+	Values []Expr
+	// Brace is the opening '{'
+	Brace Token
+}
+
+func (e MapExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitMapExpr(e)
+}
+
+func NewMapExpr(keys []Expr, values []Expr, brace Token) MapExpr {
+	return MapExpr{
+		Keys:   keys,
+		Values: values,
+		Brace:  brace,
+	}
+}
+
+// FuncExpr is an anonymous fungsi, its Declaration has no name.
+type FuncExpr struct {
+	Declaration FuncStmt
+	// Arrow is set for the short form 'fungsi (x) => x * 2', the body is
+	// then a single balikin of the expression and RightBrace of the
+	// declaration is the last token of that expression
+	Arrow bool
+}
+
+func (e FuncExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitFuncExpr(e)
+}
+
+func NewFuncExpr(declaration FuncStmt, arrow bool) FuncExpr {
+	return FuncExpr{
+		Declaration: declaration,
+		Arrow:       arrow,
+	}
+}
+
+// PropertyExpr reads the field or method Name of an objek, 'object.name'.
+type PropertyExpr struct {
+	Object Expr
+	Name   Token
+}
+
+func (e PropertyExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitPropertyExpr(e)
+}
+
+func NewPropertyExpr(object Expr, name Token) PropertyExpr {
+	return PropertyExpr{
+		Object: object,
+		Name:   name,
+	}
+}
+
+// ThisExpr is 'ini', the objek a method was called on. The resolver binds it
+// like a variable declared around the methods of the kelas.
+type ThisExpr struct {
+	Keyword Token
+	Binding *Binding
+}
+
+func (e ThisExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitThisExpr(e)
+}
+
+func NewThisExpr(keyword Token) ThisExpr {
+	return ThisExpr{Keyword: keyword, Binding: NewBinding()}
+}
+
+// SuperExpr is 'induk.method', the method of the induk kelas bound to ini. The
+// resolver binds it like a variable declared around the methods of a kelas
+// that inherits, ini is declared one scope closer.
+type SuperExpr struct {
+	Keyword Token
+	Method  Token
+	Binding *Binding
+}
+
+func (e SuperExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitSuperExpr(e)
+}
+
+func NewSuperExpr(keyword Token, method Token) SuperExpr {
+	return SuperExpr{Keyword: keyword, Method: method, Binding: NewBinding()}
+}
+
+// TemplateExpr is a teks with interpolated expressions, "a ${b} c" has the
+// segments "a ${ and } c" around its single value b.
+type TemplateExpr struct {
+	// Segments are the template tokens, there is one more than Values
+	Segments []Token
+	Values   []Expr
+}
+
+func (e TemplateExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitTemplateExpr(e)
+}
+
+func NewTemplateExpr(segments []Token, values []Expr) TemplateExpr {
+	return TemplateExpr{
+		Segments: segments,
+		Values:   values,
+	}
+}
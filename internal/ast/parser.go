@@ -3,30 +3,37 @@ package ast
 import (
 	"fmt"
 	"io"
-)
-
-type parseError struct {
-	message string
-}
+	"slices"
 
-func (e parseError) Error() string {
-	return e.message
-}
+	"github.com/aselhid/indoscript/internal/diagnostic"
+)
 
 /*
 Grammar (so far)
 ----------------
 program         -> declaration* EOF
-declaration     -> varDeclaration | statement | assignment | funcDeclaration
+declaration     -> varDeclaration | statement | funcDeclaration | classDeclaration
 funcDeclaration -> "fungsi" function
+classDeclaration -> "kelas" IDENTIFIER ( "<" IDENTIFIER )? "{" function* "}"
 function        -> IDENTIFIER "(" parameters? ")" block
-parameters      -> IDENTIFIER ( "," IDENTIFIER )*
-assignment      -> IDENTIFIER "=" expression ";"
+parameters      -> parameter ( "," parameter )* ( "," rest )? | rest
+parameter       -> IDENTIFIER ( "=" expression )?
+rest            -> "..." IDENTIFIER
+assignment      -> ( IDENTIFIER | call "[" expression "]" | call "." IDENTIFIER ) "=" expression ";"
 varDeclaration  -> "misal" IDENTIFIER "=" expresion ";"
-statement       -> exprStmt | printStmt | block | ifStmt | whileStmt | returnStmt
+statement       -> exprStmt | assignment | printStmt | block | ifStmt | loopStmt | returnStmt
+                 | breakStmt | continueStmt | throwStmt | tryStmt
 returnStmt      -> "return" expression? ";"
-whileStmt       -> "untuk" expression "{" statement "}"
-ifStmt          -> "jika" expression "{" statement "}" ( "lain" "{" statement "}" )?
+breakStmt       -> "berhenti" IDENTIFIER? ";"
+continueStmt    -> "lanjut" IDENTIFIER? ";"
+throwStmt       -> "lempar" expression ";"
+tryStmt         -> "coba" block ( "tangkap" "(" IDENTIFIER ")" block )? ( "akhirnya" block )?
+loopStmt        -> ( IDENTIFIER ":" )? ( whileStmt | forStmt )
+whileStmt       -> "selama" expression block
+forStmt         -> "untuk" ( varDeclaration | simpleStmt? ";" ) expression? ";" simpleStmt? block
+                 | "untuk" IDENTIFIER "dalam" expression block
+simpleStmt      -> expression | ( IDENTIFIER | call "[" expression "]" | call "." IDENTIFIER ) "=" expression
+ifStmt          -> "jika" expression block ( "lain" ( ifStmt | block ) )?
 block           -> "{" declaration* "}"
 exprStmt        -> expression ";"
 printStmt       -> "cetak" expression ";"
@@ -38,10 +45,20 @@ comparison      -> term ( ( ">" | ">=" | "<" | "<=" ) term )*
 term            -> factor ( ( "-" | "+" ) factor )*
 factor          -> unary ( ( "/" | "*" ) unary )*
 unary           -> ( "!" | "-" ) unary | call
-call            -> primary ( "(" arguments? ")" )*
-primary         -> FALSE | TRUE | NIL | NUMBER | STRING | group | IDENTIFIER
+call            -> primary ( "(" arguments? ")" | "[" expression "]" | "." IDENTIFIER )*
+primary         -> FALSE | TRUE | NIL | NUMBER | STRING | template | "ini" | "induk" "." IDENTIFIER | group
+                 | IDENTIFIER | list | map | funcExpr
+funcExpr        -> "fungsi" "(" parameters? ")" ( block | "=>" expression )
+template        -> TEMPLATE_START expression ( TEMPLATE_MIDDLE expression )* TEMPLATE_END
 group           -> "(" expression ")"
-arguments       -> expression ( "," expression )*
+list            -> "[" ( expression ( "," expression )* ","? )? "]"
+map             -> "{" ( entry ( "," entry )* ","? )? "}"
+entry           -> expression ":" expression
+arguments       -> argument ( "," argument )*
+argument        -> ( IDENTIFIER ":" )? expression
+
+A "{" starting a statement always opens a block, map literals are only parsed
+where an expression is expected.
 */
 
 type Parser struct {
@@ -49,84 +66,207 @@ type Parser struct {
 	tokens   []Token
 	current  int
 	hasError bool
-}
-
-func NewParser(tokens []Token) *Parser {
+	errors   []error
+	// blockDepth counts the blocks being parsed
+	blockDepth int
+	// loops holds the labels of the loops enclosing the statement being
+	// parsed, innermost last, an unlabeled loop has an empty label
+	loops []string
+}
+
+// NewParser parses tokens, comments among them are skipped.
+func NewParser(tokens []Token, stdErr io.Writer) *Parser {
+	withoutComments := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.TokenType != TokenComment {
+			withoutComments = append(withoutComments, token)
+		}
+	}
 	return &Parser{
-		tokens: tokens,
+		tokens: withoutComments,
+		stdErr: stdErr,
 	}
 }
 
-// using Expr first
+// Parse parses every declaration in the source. A syntax error does not stop
+// the parse, the parser skips to the next statement and carries on so every
+// error is reported, in which case the returned statements are incomplete.
 func (p *Parser) Parse() ([]Stmt, bool) {
+	var result []Stmt
+	for !p.isAtEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			result = append(result, stmt)
+		}
+	}
+	return result, p.hasError
+}
+
+// Errors returns every error reported while parsing.
+func (p *Parser) Errors() []error {
+	return p.errors
+}
+
+// declaration returns nil when the declaration has a syntax error, the tokens
+// up to the next statement are skipped by then.
+func (p *Parser) declaration() (stmt Stmt) {
 	defer func() {
 		if err := recover(); err != nil {
-			if _, ok := err.(parseError); ok {
-				p.hasError = true
-				p.sync()
-			} else {
+			if _, ok := err.(diagnostic.Diagnostic); !ok {
 				panic(err)
 			}
+			p.sync()
+			stmt = nil
 		}
 	}()
-	var result []Stmt
-	for !p.isAtEnd() {
-		result = append(result, p.declaration())
-	}
-	return result, p.hasError
-}
 
-func (p *Parser) declaration() Stmt {
 	switch {
-	case p.match(TokenFunction):
+	case p.peek().TokenType == TokenFunction && p.peekNext().TokenType == TokenIdentifier:
+		p.advance()
 		return p.funcDeclaration()
 	case p.match(TokenLet):
 		return p.varDeclaration()
-	case p.match(TokenIdentifier):
-		if p.match(TokenEqual) {
-			return p.assignment()
-		} else {
-			p.undoAdvance()
-		}
+	case p.match(TokenClass):
+		return p.classDeclaration()
 	}
 	return p.statement()
 }
 
-func (p *Parser) assignment() Stmt {
-	identifier := p.previous()
-	p.consume(TokenEqual, "expect '=' for declaration")
+func (p *Parser) assignment(target Expr) Stmt {
+	stmt := p.assignmentClause(target)
+	p.consume(TokenSemicolon, "expect ';' after statement")
+	return stmt
+}
 
+// assignmentClause parses the value of an assignment whose '=' was just
+// consumed, without the ';' ending the statement.
+func (p *Parser) assignmentClause(target Expr) Stmt {
+	equal := p.previous()
 	value := p.expression()
-	p.consume(TokenSemicolon, "expect ';' after statement")
-	return NewVarStmt(identifier, value)
+
+	switch target := target.(type) {
+	case VarExpr:
+		return NewAssignStmt(target.Identifier, value)
+	case IndexExpr:
+		return NewIndexAssignStmt(target.Object, target.Index, value, target.Bracket)
+	case PropertyExpr:
+		return NewPropertyAssignStmt(target.Object, target.Name, value)
+	}
+	// the parser is not confused about where the statement ends, so there
+	// is nothing to recover from
+	p.report(equal, "invalid assignment target")
+	return nil
 }
 
 func (p *Parser) varDeclaration() Stmt {
+	keyword := p.previous()
 	identifier := p.consume(TokenIdentifier, "expect variable name after 'mulai'")
 	p.consume(TokenEqual, "identifier without initialization is not allowed")
 
 	initializer := p.expression()
 	p.consume(TokenSemicolon, "expect ';' after statement")
 
-	return NewVarStmt(identifier, initializer)
+	return NewVarStmt(keyword, identifier, initializer)
 }
 
 func (p *Parser) funcDeclaration() Stmt {
+	keyword := p.previous()
 	name := p.consume(TokenIdentifier, "expect identifier after fungsi declaration")
 	p.consume(TokenLeftParenthesis, "expect opening '(' after fungsi declaration")
+	parameters, defaults, variadic := p.parameters()
+	p.consume(TokenLeftBrace, "expect opening '{' to define fungsi body")
+	body := p.functionBody()
+	return NewFuncStmt(keyword, name, parameters, defaults, variadic, body)
+}
+
+func (p *Parser) classDeclaration() Stmt {
+	keyword := p.previous()
+	name := p.consume(TokenIdentifier, "expect identifier after kelas declaration")
+	var superclass *VarExpr
+	if p.match(TokenLess) {
+		identifier := p.consume(TokenIdentifier, "expect induk kelas name after '<'")
+		expr := NewVarExpr(identifier)
+		superclass = &expr
+	}
+	p.consume(TokenLeftBrace, "expect opening '{' to define kelas body")
+	var methods []FuncStmt
+	for p.peek().TokenType != TokenRightBrace && !p.isAtEnd() {
+		methods = append(methods, p.method())
+	}
+	rightBrace := p.consume(TokenRightBrace, "expect closing '}' after kelas body")
+	return NewClassStmt(keyword, name, superclass, methods, rightBrace)
+}
+
+// method parses a method of a kelas, declared like a fungsi without the
+// keyword.
+func (p *Parser) method() FuncStmt {
+	name := p.consume(TokenIdentifier, "expect method name")
+	p.consume(TokenLeftParenthesis, "expect opening '(' after method name")
+	parameters, defaults, variadic := p.parameters()
+	p.consume(TokenLeftBrace, "expect opening '{' to define method body")
+	body := p.functionBody()
+	return NewFuncStmt(name, name, parameters, defaults, variadic, body)
+}
+
+// funcExpression parses an anonymous fungsi, its body is either a block or
+// '=>' followed by the expression it returns.
+func (p *Parser) funcExpression() Expr {
+	keyword := p.previous()
+	p.consume(TokenLeftParenthesis, "expect opening '(' after fungsi")
+	parameters, defaults, variadic := p.parameters()
+	if p.match(TokenArrow) {
+		arrow := p.previous()
+		value := p.expression()
+		body := NewBlockStmt(arrow, []Stmt{NewReturnStmt(arrow, value)}, p.previous())
+		return NewFuncExpr(NewFuncStmt(keyword, Token{}, parameters, defaults, variadic, body), true)
+	}
+	p.consume(TokenLeftBrace, "expect opening '{' or '=>' to define fungsi body")
+	body := p.functionBody()
+	return NewFuncExpr(NewFuncStmt(keyword, Token{}, parameters, defaults, variadic, body), false)
+}
+
+// functionBody parses the block of a fungsi, berhenti and lanjut can't reach
+// the loops around the fungsi.
+func (p *Parser) functionBody() BlockStmt {
+	loops := p.loops
+	p.loops = nil
+	defer func() {
+		p.loops = loops
+	}()
+	return p.block()
+}
+
+// parameters parses the parameter list after the opening '(' up to and
+// including the closing ')'.
+func (p *Parser) parameters() ([]Token, []Expr, bool) {
 	var parameters []Token
+	var defaults []Expr
+	variadic, hasDefault := false, false
 	if p.peek().TokenType != TokenRightParenthesis {
 		for {
-			parameters = append(parameters, p.consume(TokenIdentifier, "expect parameter name"))
+			if variadic {
+				p.report(p.peek(), "rest parameter must be the last parameter")
+			}
+			variadic = p.match(TokenEllipsis)
+			parameter := p.consume(TokenIdentifier, "expect parameter name")
+			var value Expr
+			if p.match(TokenEqual) {
+				if variadic {
+					p.report(p.previous(), "rest parameter can't have a default value")
+				}
+				value = p.expression()
+				hasDefault = true
+			} else if hasDefault && !variadic {
+				p.report(parameter, "parameter without a default value can't follow one with a default value")
+			}
+			parameters = append(parameters, parameter)
+			defaults = append(defaults, value)
 			if !p.match(TokenComma) {
 				break
 			}
 		}
 	}
 	p.consume(TokenRightParenthesis, "expect closing ')' after fungsi declaration")
-	p.consume(TokenLeftBrace, "expect opening '{' to define fungsi body")
-	body := p.block()
-	return NewFuncStmt(name, parameters, body)
+	return parameters, defaults, variadic
 }
 
 func (p *Parser) statement() Stmt {
@@ -134,67 +274,222 @@ func (p *Parser) statement() Stmt {
 	case p.match(TokenPrint):
 		return p.printStmt()
 	case p.match(TokenLeftBrace):
-		return NewBlockStmt(p.block())
+		return p.block()
 	case p.match(TokenIf):
 		return p.ifStmt()
 	case p.match(TokenLoop):
-		return p.whileStmt()
+		return p.whileStmt(Token{})
+	case p.match(TokenFor):
+		return p.forStmt(Token{})
 	case p.match(TokenReturn):
 		return p.returnStmt()
+	case p.match(TokenBreak, TokenContinue):
+		return p.jumpStmt()
+	case p.match(TokenThrow):
+		return p.throwStmt()
+	case p.match(TokenTry):
+		return p.tryStmt()
+	case p.peek().TokenType == TokenIdentifier && p.peekNext().TokenType == TokenColon:
+		return p.labeledStmt()
 	}
 	return p.exprStmt()
 }
 
+// labeledStmt parses a loop preceded by 'label:'.
+func (p *Parser) labeledStmt() Stmt {
+	label := p.advance()
+	p.advance()
+	if slices.Contains(p.loops, label.Lexeme) {
+		p.report(label, fmt.Sprintf("label %s is already used by an enclosing loop", label.Lexeme))
+	}
+	switch {
+	case p.match(TokenLoop):
+		return p.whileStmt(label)
+	case p.match(TokenFor):
+		return p.forStmt(label)
+	}
+	p.error(p.peek(), "expect selama or untuk after loop label")
+	return nil
+}
+
+// loopBody parses the block of a loop labeled label, which may be the zero
+// Token.
+func (p *Parser) loopBody(label Token) BlockStmt {
+	p.loops = append(p.loops, label.Lexeme)
+	defer func() {
+		p.loops = p.loops[:len(p.loops)-1]
+	}()
+	return p.block()
+}
+
+// jumpStmt parses berhenti or lanjut, both are only allowed inside a loop of
+// the fungsi they appear in.
+func (p *Parser) jumpStmt() Stmt {
+	keyword := p.previous()
+	var label Token
+	if p.match(TokenIdentifier) {
+		label = p.previous()
+	}
+	p.consume(TokenSemicolon, "expect ';' after "+keyword.Lexeme)
+
+	switch {
+	case len(p.loops) == 0:
+		p.report(keyword, fmt.Sprintf("can't use %s outside of a loop", keyword.Lexeme))
+	case label.Lexeme != "" && !slices.Contains(p.loops, label.Lexeme):
+		p.report(label, fmt.Sprintf("no enclosing loop is labeled %s", label.Lexeme))
+	}
+	if keyword.TokenType == TokenBreak {
+		return NewBreakStmt(keyword, label)
+	}
+	return NewContinueStmt(keyword, label)
+}
+
+func (p *Parser) throwStmt() Stmt {
+	keyword := p.previous()
+	value := p.expression()
+	p.consume(TokenSemicolon, "expect ';' after statement")
+	return NewThrowStmt(keyword, value)
+}
+
+// tryStmt parses a coba statement, it needs a tangkap or an akhirnya clause
+// and may have both.
+func (p *Parser) tryStmt() Stmt {
+	keyword := p.previous()
+	p.consume(TokenLeftBrace, "expect block start '{' after coba")
+	body := p.block()
+
+	var catchName Token
+	var catch, finally *BlockStmt
+	if p.match(TokenCatch) {
+		p.consume(TokenLeftParenthesis, "expect opening '(' after tangkap")
+		catchName = p.consume(TokenIdentifier, "expect variable name for the galat")
+		p.consume(TokenRightParenthesis, "expect closing ')' after galat variable")
+		p.consume(TokenLeftBrace, "expect block start '{' after tangkap")
+		block := p.block()
+		catch = &block
+	}
+	if p.match(TokenFinally) {
+		p.consume(TokenLeftBrace, "expect block start '{' after akhirnya")
+		block := p.block()
+		finally = &block
+	}
+	if catch == nil && finally == nil {
+		p.error(p.peek(), "expect tangkap or akhirnya after coba block")
+	}
+	return NewTryStmt(keyword, body, catchName, catch, finally)
+}
+
 func (p *Parser) printStmt() Stmt {
+	keyword := p.previous()
 	expr := p.expression()
 	p.consume(TokenSemicolon, "expect ';' after statement")
-	return NewPrintStmt(expr)
+	return NewPrintStmt(keyword, expr)
 }
 
 func (p *Parser) exprStmt() Stmt {
 	expr := p.expression()
+	if p.match(TokenEqual) {
+		return p.assignment(expr)
+	}
 	p.consume(TokenSemicolon, "expect ';' after statement")
 	return NewExprStmt(expr)
 }
 
-func (p *Parser) block() []Stmt {
+// block parses the statements of a block whose '{' was just consumed.
+func (p *Parser) block() BlockStmt {
+	leftBrace := p.previous()
+	p.blockDepth++
+	defer func() {
+		p.blockDepth--
+	}()
+
 	var statements []Stmt
 	for p.peek().TokenType != TokenRightBrace && !p.isAtEnd() {
-		statements = append(statements, p.declaration())
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
 	}
-	p.consume(TokenRightBrace, "expect '}' to close the scope")
+	rightBrace := p.consume(TokenRightBrace, "expect '}' to close the scope")
 
-	return statements
+	return NewBlockStmt(leftBrace, statements, rightBrace)
 }
 
 func (p *Parser) ifStmt() Stmt {
+	keyword := p.previous()
 	condition := p.expression()
 
 	p.consume(TokenLeftBrace, "expect block start after jika condition")
 	thenStmt := p.block()
 	if p.match(TokenElse) {
-		p.consume(TokenLeftBrace, "expect block start '}' after lain statement")
+		if p.match(TokenIf) {
+			return NewIfStmt(keyword, condition, thenStmt, p.ifStmt())
+		}
+		p.consume(TokenLeftBrace, "expect block start '{' or jika after lain")
 		elseStmt := p.block()
-		return NewIfStmt(condition, NewBlockStmt(thenStmt), NewBlockStmt(elseStmt))
+		return NewIfStmt(keyword, condition, thenStmt, elseStmt)
 	}
-	return NewIfStmt(condition, NewBlockStmt(thenStmt), NewBlockStmt(nil))
+	return NewIfStmt(keyword, condition, thenStmt, nil)
 }
 
-func (p *Parser) whileStmt() Stmt {
+func (p *Parser) whileStmt(label Token) Stmt {
+	keyword := p.previous()
 	condition := p.expression()
 
 	p.consume(TokenLeftBrace, "expect block start '{' after selama statement ")
-	stmt := p.block()
-	return NewWhileStmt(condition, NewBlockStmt(stmt))
+	stmt := p.loopBody(label)
+	return NewWhileStmt(label, keyword, condition, stmt)
+}
+
+func (p *Parser) forStmt(label Token) Stmt {
+	keyword := p.previous()
+	if p.peek().TokenType == TokenIdentifier && p.peekNext().TokenType == TokenIn {
+		variable := p.advance()
+		p.advance()
+		iterable := p.expression()
+		p.consume(TokenLeftBrace, "expect block start '{' after untuk statement")
+		return NewForInStmt(label, keyword, variable, iterable, p.loopBody(label))
+	}
+
+	var initializer Stmt
+	switch {
+	case p.match(TokenLet):
+		initializer = p.varDeclaration()
+	case p.match(TokenSemicolon):
+	default:
+		initializer = p.simpleStmt()
+		p.consume(TokenSemicolon, "expect ';' after untuk initializer")
+	}
+	var condition Expr
+	if p.peek().TokenType != TokenSemicolon {
+		condition = p.expression()
+	}
+	p.consume(TokenSemicolon, "expect ';' after untuk condition")
+	var increment Stmt
+	if p.peek().TokenType != TokenLeftBrace {
+		increment = p.simpleStmt()
+	}
+	p.consume(TokenLeftBrace, "expect block start '{' after untuk statement")
+	return NewForStmt(label, keyword, initializer, condition, increment, p.loopBody(label))
+}
+
+// simpleStmt parses an expression or an assignment not followed by a ';', as
+// found in the clauses of an untuk loop.
+func (p *Parser) simpleStmt() Stmt {
+	expr := p.expression()
+	if p.match(TokenEqual) {
+		return p.assignmentClause(expr)
+	}
+	return NewExprStmt(expr)
 }
 
 func (p *Parser) returnStmt() Stmt {
+	keyword := p.previous()
 	var value Expr
 	if p.peek().TokenType != TokenSemicolon {
 		value = p.expression()
 	}
 	p.consume(TokenSemicolon, "expect ';' after return statement")
-	return NewReturnStmt(value)
+	return NewReturnStmt(keyword, value)
 }
 
 func (p *Parser) expression() Expr {
@@ -273,48 +568,131 @@ func (p *Parser) unary() Expr {
 func (p *Parser) call() Expr {
 	expr := p.primary()
 
-	for p.match(TokenLeftParenthesis) {
-		expr = p.finishCall(expr)
+	for {
+		switch {
+		case p.match(TokenLeftParenthesis):
+			expr = p.finishCall(expr)
+		case p.match(TokenLeftBracket):
+			index := p.expression()
+			bracket := p.consume(TokenRightBracket, "expect closing ']' after index")
+			expr = NewIndexExpr(expr, index, bracket)
+		case p.match(TokenDot):
+			name := p.consume(TokenIdentifier, "expect property name after '.'")
+			expr = NewPropertyExpr(expr, name)
+		default:
+			return expr
+		}
 	}
-
-	return expr
 }
 
 func (p *Parser) finishCall(callee Expr) Expr {
 	var arguments []Expr
+	var names []Token
+	named := false
 	if p.peek().TokenType != TokenRightParenthesis {
 		for {
+			var name Token
+			if p.peek().TokenType == TokenIdentifier && p.peekNext().TokenType == TokenColon {
+				name = p.advance()
+				p.advance()
+				named = true
+			} else if named {
+				p.report(p.peek(), "positional argument can't follow a named argument")
+			}
 			arguments = append(arguments, p.expression())
+			names = append(names, name)
 			if !p.match(TokenComma) {
 				break
 			}
 		}
 	}
+	if !named {
+		names = nil
+	}
 	parenthesis := p.consume(TokenRightParenthesis, "expect closing ')' after fungsi call")
-	return NewCallExpr(callee, arguments, parenthesis)
+	return NewCallExpr(callee, arguments, names, parenthesis)
 }
 
 func (p *Parser) primary() Expr {
 	switch {
 	case p.match(TokenFalse):
-		return NewPrimaryExpr(false)
+		return NewPrimaryExpr(p.previous(), false)
 	case p.match(TokenTrue):
-		return NewPrimaryExpr(true)
+		return NewPrimaryExpr(p.previous(), true)
 	case p.match(TokenNil):
-		return NewPrimaryExpr(nil)
+		return NewPrimaryExpr(p.previous(), nil)
 	case p.match(TokenNumber, TokenString):
-		return NewPrimaryExpr(p.previous().Literal)
+		return NewPrimaryExpr(p.previous(), p.previous().Literal)
+	case p.match(TokenTemplateStart):
+		return p.template()
 	case p.match(TokenIdentifier):
 		return NewVarExpr(p.previous())
+	case p.match(TokenThis):
+		return NewThisExpr(p.previous())
+	case p.match(TokenSuper):
+		keyword := p.previous()
+		p.consume(TokenDot, "expect '.' after induk")
+		method := p.consume(TokenIdentifier, "expect induk method name after '.'")
+		return NewSuperExpr(keyword, method)
+	case p.match(TokenFunction):
+		return p.funcExpression()
 	case p.match(TokenLeftParenthesis):
+		parenthesis := p.previous()
 		expr := p.expression()
-		p.consume(TokenRightParenthesis, "Expect ')' after using '(' to group expression.")
-		return expr
+		p.consume(TokenRightParenthesis, "expect ')' after using '(' to group expression")
+		return NewGroupExpr(parenthesis, expr)
+	case p.match(TokenLeftBracket):
+		return p.list()
+	case p.match(TokenLeftBrace):
+		return p.mapLiteral()
 	}
-	p.error(p.peek(), fmt.Sprintf("expecting expression, got %+v", p.previous()))
+	p.error(p.peek(), "expect expression")
 	return nil
 }
 
+// template parses the expressions interpolated into a teks, each one is
+// followed by the segment after it until the segment closing the teks.
+func (p *Parser) template() Expr {
+	segments := []Token{p.previous()}
+	var values []Expr
+	for {
+		values = append(values, p.expression())
+		if p.match(TokenTemplateEnd) {
+			segments = append(segments, p.previous())
+			return NewTemplateExpr(segments, values)
+		}
+		segments = append(segments, p.consume(TokenTemplateMiddle, "expect closing '}' after interpolated expression"))
+	}
+}
+
+func (p *Parser) list() Expr {
+	bracket := p.previous()
+	var elements []Expr
+	for p.peek().TokenType != TokenRightBracket && !p.isAtEnd() {
+		elements = append(elements, p.expression())
+		if !p.match(TokenComma) {
+			break
+		}
+	}
+	p.consume(TokenRightBracket, "expect closing ']' after list elements")
+	return NewListExpr(elements, bracket)
+}
+
+func (p *Parser) mapLiteral() Expr {
+	brace := p.previous()
+	var keys, values []Expr
+	for p.peek().TokenType != TokenRightBrace && !p.isAtEnd() {
+		keys = append(keys, p.expression())
+		p.consume(TokenColon, "expect ':' between map key and value")
+		values = append(values, p.expression())
+		if !p.match(TokenComma) {
+			break
+		}
+	}
+	p.consume(TokenRightBrace, "expect closing '}' after map entries")
+	return NewMapExpr(keys, values, brace)
+}
+
 func (p *Parser) consume(tokenType TokenType, errMessage string) Token {
 	if p.peek().TokenType == tokenType {
 		return p.advance()
@@ -348,39 +726,58 @@ func (p *Parser) advance() Token {
 	return p.previous()
 }
 
-func (p *Parser) undoAdvance() {
-	if p.current > 0 {
-		p.current--
+// peekNext returns the token after the next one, or the next one when that
+// is the end of the file.
+func (p *Parser) peekNext() Token {
+	if p.isAtEnd() {
+		return p.peek()
 	}
+	return p.tokens[p.current+1]
 }
 
 func (p *Parser) previous() Token {
 	return p.tokens[p.current-1]
 }
 
+// sync discards tokens until the start of the next statement, either right
+// after a ';' or before a keyword starting a statement. Inside a block it also
+// stops before a '}' and leaves it for the block to consume, so an error in the
+// last statement of a block does not swallow the code after it.
 func (p *Parser) sync() {
+	// the error was reported at a token that already starts a statement
+	if p.atStatementStart() {
+		return
+	}
 	p.advance()
 
-	for p.isAtEnd() {
-		if p.previous().TokenType == TokenSemicolon {
-			return
-		}
-
-		switch p.peek().TokenType {
-		case TokenFunction, TokenLet, TokenLoop, TokenIf, TokenPrint, TokenReturn:
+	for !p.isAtEnd() {
+		if p.previous().TokenType == TokenSemicolon || p.atStatementStart() {
 			return
 		}
 		p.advance()
 	}
 }
 
+func (p *Parser) atStatementStart() bool {
+	switch p.peek().TokenType {
+	case TokenFunction, TokenClass, TokenLet, TokenLoop, TokenFor, TokenIf, TokenPrint, TokenReturn, TokenBreak, TokenContinue,
+		TokenThrow, TokenTry:
+		return true
+	case TokenRightBrace:
+		return p.blockDepth > 0
+	}
+	return false
+}
+
+// error reports a syntax error and unwinds to the enclosing declaration.
 func (p *Parser) error(token Token, errMessage string) {
-	fmt.Println(errMessage)
-	location := "at the end of file"
-	if token.TokenType != TokenEof {
-		location = fmt.Sprintf("at '%s'", token.Lexeme)
-	}
-	err := parseError{message: fmt.Sprintf("[line %d] Error %s: %s\n", token.LineNumber, location, errMessage)}
-	p.stdErr.Write([]byte(err.Error()))
-	panic(err)
+	panic(p.report(token, errMessage))
+}
+
+func (p *Parser) report(token Token, errMessage string) diagnostic.Diagnostic {
+	err := diagnostic.NewError(token.Span(), diagnostic.CodeSyntax, errMessage)
+	p.hasError = true
+	p.errors = append(p.errors, err)
+	p.stdErr.Write([]byte(err.Error() + "\n"))
+	return err
 }
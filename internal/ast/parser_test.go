@@ -0,0 +1,160 @@
+package ast_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
+	"github.com/aselhid/indoscript/internal/lexer"
+)
+
+type expectedError struct {
+	line    int
+	message string
+}
+
+func TestParseReportsEveryError(t *testing.T) {
+	testcases := map[string]struct {
+		source   string
+		expected []expectedError
+	}{
+		"statements": {
+			source: "misal a = ;\ncetak 1 +;\ncetak a;\nmisal = 2;",
+			expected: []expectedError{
+				{1, "expect expression"},
+				{2, "expect expression"},
+				{4, "expect variable name after 'mulai'"},
+			},
+		},
+		"missing semicolons": {
+			source: "cetak 1\nmisal a = 2\nmisal b = 3;\ncetak b",
+			expected: []expectedError{
+				{2, "expect ';' after statement"},
+				{3, "expect ';' after statement"},
+				{4, "expect ';' after statement"},
+			},
+		},
+		"inside blocks": {
+			source: "fungsi f() {\n  cetak 1 +;\n  balikin\n}\njika benar {\n  misal = 1;\n}\ncetak (;",
+			expected: []expectedError{
+				{2, "expect expression"},
+				{4, "expect expression"},
+				{6, "expect variable name after 'mulai'"},
+				{8, "expect expression"},
+			},
+		},
+		"invalid assignment target": {
+			source: "1 = 2;\nf() = 3;\ncetak 1;",
+			expected: []expectedError{
+				{1, "invalid assignment target"},
+				{2, "invalid assignment target"},
+			},
+		},
+		"unclosed": {
+			source: "misal xs = [1, 2;\nfungsi f(a, {\n",
+			expected: []expectedError{
+				{1, "expect closing ']' after list elements"},
+				{2, "expect parameter name"},
+			},
+		},
+		"anonymous function": {
+			source: "misal f = fungsi x {};\nmisal g = fungsi () balikin 1;\ncetak 1;",
+			expected: []expectedError{
+				{1, "expect opening '(' after fungsi"},
+				{2, "expect opening '{' or '=>' to define fungsi body"},
+			},
+		},
+		"untuk": {
+			source: "untuk misal i = 0; i < 3 { }\nuntuk x dalam xs cetak x;\nuntuk ; ; i = 1; { }",
+			expected: []expectedError{
+				{1, "expect ';' after untuk condition"},
+				{2, "expect block start '{' after untuk statement"},
+				{3, "expect block start '{' after untuk statement"},
+			},
+		},
+		"berhenti and lanjut": {
+			source: "berhenti;\nselama benar { fungsi f() { lanjut; } }\n" +
+				"luar: selama benar { berhenti dalam_; }\nluar: untuk x dalam xs { luar: selama benar {} }\nx: cetak 1;",
+			expected: []expectedError{
+				{1, "can't use berhenti outside of a loop"},
+				{2, "can't use lanjut outside of a loop"},
+				{3, "no enclosing loop is labeled dalam_"},
+				{4, "label luar is already used by an enclosing loop"},
+				{5, "expect selama or untuk after loop label"},
+			},
+		},
+		"lain": {
+			source: "jika a {} lain cetak 1;\njika a {} lain jika b cetak 2;\ncetak 1;",
+			expected: []expectedError{
+				{1, "expect block start '{' or jika after lain"},
+				{2, "expect block start after jika condition"},
+			},
+		},
+		"interpolation": {
+			source: "cetak \"a ${}\";\ncetak \"b ${1 2}\";\ncetak 1;",
+			expected: []expectedError{
+				{1, "expect expression"},
+				{2, "expect closing '}' after interpolated expression"},
+			},
+		},
+		"rest parameter": {
+			source: "fungsi f(...a, b) {}\nfungsi g(...) {}",
+			expected: []expectedError{
+				{1, "rest parameter must be the last parameter"},
+				{2, "expect parameter name"},
+			},
+		},
+	}
+	for name, testcase := range testcases {
+		t.Run(name, func(t *testing.T) {
+			tokens := lexer.NewScanner(strings.NewReader(testcase.source), io.Discard).ScanTokens()
+			parser := ast.NewParser(tokens, io.Discard)
+			_, hasError := parser.Parse()
+			if !hasError {
+				t.Fatal("expected the parse to fail")
+			}
+			compareErrors(t, testcase.expected, parser.Errors())
+		})
+	}
+}
+
+func TestParseValidProgram(t *testing.T) {
+	source := `
+misal a = 1;
+fungsi f(x) {
+    jika x > 1 { balikin x; } lain { balikin [x, {"a": x}]; }
+}
+selama a < 3 { a = a + 1; }
+untuk misal i = 0; i < 3; i = i + 1 { a = a + i; }
+untuk x dalam [a] { cetak x; }
+cetak f(a)[0];
+`
+	tokens := lexer.NewScanner(strings.NewReader(source), io.Discard).ScanTokens()
+	parser := ast.NewParser(tokens, io.Discard)
+	stmts, hasError := parser.Parse()
+	if hasError {
+		t.Fatalf("unexpected errors %v", parser.Errors())
+	}
+	if len(stmts) != 6 {
+		t.Fatalf("expected 6 statements while actual is %d", len(stmts))
+	}
+}
+
+func compareErrors(t *testing.T, expected []expectedError, actual []error) {
+	t.Helper()
+	if len(expected) != len(actual) {
+		t.Fatalf("expected %d errors while actual is %d: %v", len(expected), len(actual), actual)
+	}
+	for i, err := range actual {
+		d, ok := err.(diagnostic.Diagnostic)
+		if !ok {
+			t.Fatalf("expected a diagnostic, got %#v", err)
+		}
+		if d.Span.Start.Line != expected[i].line || d.Message != expected[i].message {
+			t.Fatalf("expected error %d to be %q at line %d while actual is %q at line %d",
+				i, expected[i].message, expected[i].line, d.Message, d.Span.Start.Line)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ast
+
+// StmtStart returns the first token of stmt.
+func StmtStart(stmt Stmt) Token {
+	switch s := stmt.(type) {
+	case PrintStmt:
+		return s.Keyword
+	case ExprStmt:
+		return ExprStart(s.Expression)
+	case VarStmt:
+		return s.Keyword
+	case AssignStmt:
+		return s.Identifier
+	case IndexAssignStmt:
+		return ExprStart(s.Object)
+	case PropertyAssignStmt:
+		return ExprStart(s.Object)
+	case BlockStmt:
+		return s.LeftBrace
+	case IfStmt:
+		return s.Keyword
+	case WhileStmt:
+		return loopStart(s.Label, s.Keyword)
+	case ForStmt:
+		return loopStart(s.Label, s.Keyword)
+	case ForInStmt:
+		return loopStart(s.Label, s.Keyword)
+	case FuncStmt:
+		return s.Keyword
+	case ClassStmt:
+		return s.Keyword
+	case ReturnStmt:
+		return s.Keyword
+	case BreakStmt:
+		return s.Keyword
+	case ContinueStmt:
+		return s.Keyword
+	case ThrowStmt:
+		return s.Keyword
+	case TryStmt:
+		return s.Keyword
+	}
+	return Token{}
+}
+
+// loopStart returns the label of a labeled loop and its keyword otherwise.
+func loopStart(label, keyword Token) Token {
+	if label.Lexeme != "" {
+		return label
+	}
+	return keyword
+}
+
+// ExprStart returns the first token of expr.
+func ExprStart(expr Expr) Token {
+	switch e := expr.(type) {
+	case BinaryExpr:
+		return ExprStart(e.Left)
+	case LogicalExpr:
+		return ExprStart(e.Left)
+	case UnaryExpr:
+		return e.Operator
+	case PrimaryExpr:
+		return e.Token
+	case GroupExpr:
+		return e.Parenthesis
+	case VarExpr:
+		return e.Identifier
+	case CallExpr:
+		return ExprStart(e.Callee)
+	case ListExpr:
+		return e.Bracket
+	case IndexExpr:
+		return ExprStart(e.Object)
+	case MapExpr:
+		return e.Brace
+	case FuncExpr:
+		return e.Declaration.Keyword
+	case PropertyExpr:
+		return ExprStart(e.Object)
+	case ThisExpr:
+		return e.Keyword
+	case SuperExpr:
+		return e.Keyword
+	case TemplateExpr:
+		return e.Segments[0]
+	}
+	return Token{}
+}
@@ -1,38 +1,77 @@
 package ast
 
 type StmtVisitor interface {
-	VisitPrintStmt(stmt PrintStmt)
-	VisitExprStmt(stmt ExprStmt)
-	VisitVarStmt(stmt VarStmt)
-	VisitBlockStmt(stmt BlockStmt)
-	VisitIfStmt(stmt IfStmt)
-	VisitWhileStmt(stmt WhileStmt)
-	VisitFuncStmt(stmt FuncStmt)
-	VisitReturnStmt(stmt ReturnStmt)
+	VisitPrintStmt(stmt PrintStmt) Completion
+	VisitExprStmt(stmt ExprStmt) Completion
+	VisitVarStmt(stmt VarStmt) Completion
+	VisitAssignStmt(stmt AssignStmt) Completion
+	VisitIndexAssignStmt(stmt IndexAssignStmt) Completion
+	VisitPropertyAssignStmt(stmt PropertyAssignStmt) Completion
+	VisitBlockStmt(stmt BlockStmt) Completion
+	VisitIfStmt(stmt IfStmt) Completion
+	VisitWhileStmt(stmt WhileStmt) Completion
+	VisitForStmt(stmt ForStmt) Completion
+	VisitForInStmt(stmt ForInStmt) Completion
+	VisitFuncStmt(stmt FuncStmt) Completion
+	VisitClassStmt(stmt ClassStmt) Completion
+	VisitReturnStmt(stmt ReturnStmt) Completion
+	VisitBreakStmt(stmt BreakStmt) Completion
+	VisitContinueStmt(stmt ContinueStmt) Completion
+	VisitThrowStmt(stmt ThrowStmt) Completion
+	VisitTryStmt(stmt TryStmt) Completion
 }
 
 type Stmt interface {
-	Accept(visitor StmtVisitor)
+	Accept(visitor StmtVisitor) Completion
+}
+
+// CompletionKind tells how executing a statement finished.
+type CompletionKind uint8
+
+const (
+	// CompletionNormal lets execution carry on with the next statement.
+	CompletionNormal CompletionKind = iota
+	// CompletionReturn unwinds to the enclosing fungsi with Value.
+	CompletionReturn
+	// CompletionBreak unwinds to the loop named by Label, or the innermost
+	// loop when Label is empty, and ends it.
+	CompletionBreak
+	// CompletionContinue unwinds to the loop named by Label, or the innermost
+	// loop when Label is empty, and starts its next iteration.
+	CompletionContinue
+	// CompletionError unwinds the whole script with Err.
+	CompletionError
+)
+
+// Completion is the result of executing a statement. Statements that don't
+// complete normally are propagated outwards until a statement handles them,
+// a fungsi call handles a return and a loop handles a break or continue.
+type Completion struct {
+	Kind  CompletionKind
+	Value any
+	Err   error
+	Label string
 }
 
 type PrintStmt struct {
+	Keyword    Token
 	Expression Expr
 }
 
-func (s PrintStmt) Accept(visitor StmtVisitor) {
-	visitor.VisitPrintStmt(s)
+func (s PrintStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitPrintStmt(s)
 }
 
-func NewPrintStmt(expression Expr) PrintStmt {
-	return PrintStmt{Expression: expression}
+func NewPrintStmt(keyword Token, expression Expr) PrintStmt {
+	return PrintStmt{Keyword: keyword, Expression: expression}
 }
 
 type ExprStmt struct {
 	Expression Expr
 }
 
-func (s ExprStmt) Accept(visitor StmtVisitor) {
-	visitor.VisitExprStmt(s)
+func (s ExprStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitExprStmt(s)
 }
 
 func NewExprStmt(expression Expr) ExprStmt {
@@ -40,92 +79,343 @@ func NewExprStmt(expression Expr) ExprStmt {
 }
 
 type VarStmt struct {
+	Keyword    Token
+	Identifier Token
+	Expression Expr
+}
+
+func (s VarStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitVarStmt(s)
+}
+
+func NewVarStmt(keyword Token, identifier Token, expression Expr) VarStmt {
+	return VarStmt{Keyword: keyword, Identifier: identifier, Expression: expression}
+}
+
+type AssignStmt struct {
 	Identifier Token
 	Expression Expr
+	Binding    *Binding
+}
+
+func (s AssignStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitAssignStmt(s)
 }
 
-func (s VarStmt) Accept(visitor StmtVisitor) {
-	visitor.VisitVarStmt(s)
+func NewAssignStmt(identifier Token, expression Expr) AssignStmt {
+	return AssignStmt{Identifier: identifier, Expression: expression, Binding: NewBinding()}
+}
+
+type IndexAssignStmt struct {
+	Object     Expr
+	Index      Expr
+	Expression Expr
+	Bracket    Token
 }
 
-func NewVarStmt(identifier Token, expression Expr) VarStmt {
-	return VarStmt{Identifier: identifier, Expression: expression}
+func (s IndexAssignStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitIndexAssignStmt(s)
+}
+
+func NewIndexAssignStmt(object Expr, index Expr, expression Expr, bracket Token) IndexAssignStmt {
+	return IndexAssignStmt{
+		Object:     object,
+		Index:      index,
+		Expression: expression,
+		Bracket:    bracket,
+	}
+}
+
+// PropertyAssignStmt sets the field Name of an objek, 'object.name = value;'.
+type PropertyAssignStmt struct {
+	Object     Expr
+	Name       Token
+	Expression Expr
+}
+
+func (s PropertyAssignStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitPropertyAssignStmt(s)
+}
+
+func NewPropertyAssignStmt(object Expr, name Token, expression Expr) PropertyAssignStmt {
+	return PropertyAssignStmt{
+		Object:     object,
+		Name:       name,
+		Expression: expression,
+	}
 }
 
 type BlockStmt struct {
+	LeftBrace  Token
 	Statements []Stmt
+	RightBrace Token
 }
 
-func (s BlockStmt) Accept(visitor StmtVisitor) {
-	visitor.VisitBlockStmt(s)
+func (s BlockStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitBlockStmt(s)
 }
 
-func NewBlockStmt(statements []Stmt) BlockStmt {
-	return BlockStmt{Statements: statements}
+func NewBlockStmt(leftBrace Token, statements []Stmt, rightBrace Token) BlockStmt {
+	return BlockStmt{
+		LeftBrace:  leftBrace,
+		Statements: statements,
+		RightBrace: rightBrace,
+	}
 }
 
 type IfStmt struct {
+	Keyword   Token
 	Condition Expr
 	ThenStmt  BlockStmt
-	ElseStmt  BlockStmt
+	// ElseStmt is the BlockStmt after lain, or the IfStmt of a 'lain jika'
+	// branch, nil when there is no lain
+	ElseStmt Stmt
+}
+
+func (s IfStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitIfStmt(s)
 }
 
-func (s IfStmt) Accept(visitor StmtVisitor) {
-	visitor.VisitIfStmt(s)
+// HasElse reports whether the lain branch was written in the source.
+func (s IfStmt) HasElse() bool {
+	return s.ElseStmt != nil
 }
 
-func NewIfStmt(condition Expr, thenStmt BlockStmt, elseStmt BlockStmt) IfStmt {
+func NewIfStmt(keyword Token, condition Expr, thenStmt BlockStmt, elseStmt Stmt) IfStmt {
 	return IfStmt{
+		Keyword:   keyword,
 		Condition: condition,
 		ThenStmt:  thenStmt,
 		ElseStmt:  elseStmt,
 	}
 }
 
+// WhileStmt is a selama loop. Label is the zero Token unless the loop is
+// labeled for berhenti and lanjut, the same goes for the other loops.
 type WhileStmt struct {
+	Label     Token
+	Keyword   Token
 	Condition Expr
 	Stmt      BlockStmt
 }
 
-func (s WhileStmt) Accept(visitor StmtVisitor) {
-	visitor.VisitWhileStmt(s)
+func (s WhileStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitWhileStmt(s)
 }
 
-func NewWhileStmt(condition Expr, blockStmt BlockStmt) WhileStmt {
+func NewWhileStmt(label Token, keyword Token, condition Expr, blockStmt BlockStmt) WhileStmt {
 	return WhileStmt{
+		Label:     label,
+		Keyword:   keyword,
 		Condition: condition,
 		Stmt:      blockStmt,
 	}
 }
 
+// ForStmt is the C-style 'untuk init; condition; increment { ... }' loop,
+// each of the three clauses may be left out. Variables declared by the
+// initializer are copied for every iteration so closures created in the body
+// keep the values of that iteration.
+type ForStmt struct {
+	Label       Token
+	Keyword     Token
+	Initializer Stmt
+	Condition   Expr
+	Increment   Stmt
+	Body        BlockStmt
+}
+
+func (s ForStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitForStmt(s)
+}
+
+func NewForStmt(label Token, keyword Token, initializer Stmt, condition Expr, increment Stmt, body BlockStmt) ForStmt {
+	return ForStmt{
+		Label:       label,
+		Keyword:     keyword,
+		Initializer: initializer,
+		Condition:   condition,
+		Increment:   increment,
+		Body:        body,
+	}
+}
+
+// ForInStmt is the 'untuk variable dalam iterable { ... }' loop, the variable
+// is declared anew for every element.
+type ForInStmt struct {
+	Label    Token
+	Keyword  Token
+	Variable Token
+	Iterable Expr
+	Body     BlockStmt
+}
+
+func (s ForInStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitForInStmt(s)
+}
+
+func NewForInStmt(label Token, keyword Token, variable Token, iterable Expr, body BlockStmt) ForInStmt {
+	return ForInStmt{
+		Label:    label,
+		Keyword:  keyword,
+		Variable: variable,
+		Iterable: iterable,
+		Body:     body,
+	}
+}
+
 type FuncStmt struct {
+	Keyword    Token
 	Name       Token
 	Parameters []Token
-	Body       []Stmt
+	// Defaults holds the default value of every parameter, nil for the
+	// parameters a call must give an argument for. The defaults are
+	// evaluated at call time in the function's scope.
+	Defaults []Expr
+	// Variadic is set when the last parameter is declared with '...' and
+	// collects the arguments left over by the others into a list
+	Variadic bool
+	Body     []Stmt
+	// RightBrace closes the body
+	RightBrace Token
 }
 
-func (s FuncStmt) Accept(visitor StmtVisitor) {
-	visitor.VisitFuncStmt(s)
+func (s FuncStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitFuncStmt(s)
 }
 
-func NewFuncStmt(name Token, parameters []Token, body []Stmt) FuncStmt {
+func NewFuncStmt(keyword Token, name Token, parameters []Token, defaults []Expr, variadic bool, body BlockStmt) FuncStmt {
 	return FuncStmt{
+		Keyword:    keyword,
 		Name:       name,
 		Parameters: parameters,
-		Body:       body,
+		Defaults:   defaults,
+		Variadic:   variadic,
+		Body:       body.Statements,
+		RightBrace: body.RightBrace,
 	}
 }
 
 type ReturnStmt struct {
-	Value Expr
+	Keyword Token
+	Value   Expr
 }
 
-func (s ReturnStmt) Accept(visitor StmtVisitor) {
-	visitor.VisitReturnStmt(s)
+func (s ReturnStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitReturnStmt(s)
 }
 
-func NewReturnStmt(value Expr) ReturnStmt {
+func NewReturnStmt(keyword Token, value Expr) ReturnStmt {
 	return ReturnStmt{
-		Value: value,
+		Keyword: keyword,
+		Value:   value,
+	}
+}
+
+// BreakStmt is 'berhenti', it ends the loop named by Label or the innermost
+// loop when Label is the zero Token.
+type BreakStmt struct {
+	Keyword Token
+	Label   Token
+}
+
+func (s BreakStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitBreakStmt(s)
+}
+
+func NewBreakStmt(keyword Token, label Token) BreakStmt {
+	return BreakStmt{
+		Keyword: keyword,
+		Label:   label,
+	}
+}
+
+// ContinueStmt is 'lanjut', it skips to the next iteration of the loop named
+// by Label or the innermost loop when Label is the zero Token.
+type ContinueStmt struct {
+	Keyword Token
+	Label   Token
+}
+
+func (s ContinueStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitContinueStmt(s)
+}
+
+func NewContinueStmt(keyword Token, label Token) ContinueStmt {
+	return ContinueStmt{
+		Keyword: keyword,
+		Label:   label,
+	}
+}
+
+// ThrowStmt is 'lempar value;', it raises Value as an error the nearest
+// enclosing coba statement catches.
+type ThrowStmt struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (s ThrowStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitThrowStmt(s)
+}
+
+func NewThrowStmt(keyword Token, value Expr) ThrowStmt {
+	return ThrowStmt{
+		Keyword: keyword,
+		Value:   value,
+	}
+}
+
+// TryStmt is 'coba { } tangkap (e) { } akhirnya { }'. An error raised in Body
+// runs Catch with the galat bound to CatchName, Finally runs however the
+// statement ends. Either Catch or Finally is nil when its clause is absent.
+type TryStmt struct {
+	Keyword   Token
+	Body      BlockStmt
+	CatchName Token
+	Catch     *BlockStmt
+	Finally   *BlockStmt
+}
+
+func (s TryStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitTryStmt(s)
+}
+
+func NewTryStmt(keyword Token, body BlockStmt, catchName Token, catch *BlockStmt, finally *BlockStmt) TryStmt {
+	return TryStmt{
+		Keyword:   keyword,
+		Body:      body,
+		CatchName: catchName,
+		Catch:     catch,
+		Finally:   finally,
+	}
+}
+
+// InitializerName is the name of the method that sets up a new objek, it runs
+// with the arguments given when the kelas is called.
+const InitializerName = "inisialisasi"
+
+// ClassStmt declares a kelas. Methods are written without the fungsi keyword,
+// the Keyword of their FuncStmt is their name. Superclass is nil unless the
+// kelas inherits from another one, 'kelas Anak < Induk'.
+type ClassStmt struct {
+	Keyword    Token
+	Name       Token
+	Superclass *VarExpr
+	Methods    []FuncStmt
+	RightBrace Token
+}
+
+func (s ClassStmt) Accept(visitor StmtVisitor) Completion {
+	return visitor.VisitClassStmt(s)
+}
+
+func NewClassStmt(keyword Token, name Token, superclass *VarExpr, methods []FuncStmt, rightBrace Token) ClassStmt {
+	return ClassStmt{
+		Keyword:    keyword,
+		Name:       name,
+		Superclass: superclass,
+		Methods:    methods,
+		RightBrace: rightBrace,
 	}
 }
@@ -1,5 +1,7 @@
 package ast
 
+import "github.com/aselhid/indoscript/internal/diagnostic"
+
 type TokenType uint8
 
 const (
@@ -16,34 +18,58 @@ const (
 	TokenPrint                     // cetak -- TODO: make as part of std library
 	TokenAnd                       // dan
 	TokenOr                        // atau
+	TokenFor                       // untuk
+	TokenIn                        // dalam
+	TokenBreak                     // berhenti
+	TokenContinue                  // lanjut
+	TokenClass                     // kelas
+	TokenThis                      // ini
+	TokenSuper                     // induk
+	TokenThrow                     // lempar
+	TokenTry                       // coba
+	TokenCatch                     // tangkap
+	TokenFinally                   // akhirnya
 
 	// Single character token
 	TokenLeftParenthesis  // (
 	TokenRightParenthesis // )
 	TokenLeftBrace        // {
 	TokenRightBrace       // }
+	TokenLeftBracket      // [
+	TokenRightBracket     // ]
 	TokenComma            // ,
 	TokenDot              // .
 	TokenPlus             // +
 	TokenMinus            // -
 	TokenSemicolon        // ;
+	TokenColon            // :
 	TokenStar             // *
 
 	// Single & double characters token
 	TokenSlash        // /
 	TokenEqual        // =
 	TokenEqualEqual   // ==
+	TokenArrow        // =>
 	TokenBang         // !
 	TokenBangEqual    // !=
 	TokenGreater      // >
 	TokenGreaterEqual // >=
 	TokenLess         // <
 	TokenLessEqual    // <=
+	TokenEllipsis     // ...
 
 	// Literals
 	TokenIdentifier
 	TokenString
 	TokenNumber
+	// A string holding ${...} is scanned as template segments around the
+	// tokens of the interpolated expressions
+	TokenTemplateStart  // "...${
+	TokenTemplateMiddle // }...${
+	TokenTemplateEnd    // }..."
+
+	// TokenComment is only produced by Scanner.ScanTokensWithComments
+	TokenComment
 
 	TokenEof
 )
@@ -53,4 +79,24 @@ type Token struct {
 	Lexeme     string
 	TokenType  TokenType
 	LineNumber int
+	// Column is the 1-based column of the first rune of the token, counted in
+	// runes, and Offset its 0-based byte offset in the source.
+	Column int
+	Offset int
+}
+
+// Span returns the range of source covered by the token.
+func (t Token) Span() diagnostic.Span {
+	start := diagnostic.Position{Line: t.LineNumber, Column: t.Column, Offset: t.Offset}
+	end := start
+	end.Offset += len(t.Lexeme)
+	for _, r := range t.Lexeme {
+		if r == '\n' {
+			end.Line++
+			end.Column = 1
+		} else {
+			end.Column++
+		}
+	}
+	return diagnostic.Span{Start: start, End: end}
 }
@@ -0,0 +1,591 @@
+// Package dap implements a Debug Adapter Protocol server for indoscript over
+// stdio. It runs a script on the tree walking interpreter and pauses it from
+// the interpreter hook for line breakpoints and stepping.
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
+	"github.com/aselhid/indoscript/internal/environment"
+	"github.com/aselhid/indoscript/internal/interpreter"
+	"github.com/aselhid/indoscript/internal/lexer"
+	"github.com/aselhid/indoscript/internal/resolver"
+)
+
+// threadID is the only thread there is, scripts are single threaded.
+const threadID = 1
+
+type stepMode uint8
+
+const (
+	modeRun stepMode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+type Server struct {
+	in *bufio.Reader
+
+	// outMu guards out and seq, responses are written by the request loop
+	// while events also come from the script goroutine
+	outMu sync.Mutex
+	out   io.Writer
+	seq   int
+
+	program     string
+	stmts       []ast.Stmt
+	stmtLines   map[int]bool
+	stopOnEntry bool
+	launched    bool
+	configured  bool
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	// mu guards the state shared with the hook running on the script
+	// goroutine
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	mode        stepMode
+	stepDepth   int
+	pause       bool
+	stopping    bool
+	globals     *environment.Environment
+	// frames is a snapshot of the interpreter frames, innermost first, and is
+	// only set while the script is stopped
+	frames    []interpreter.Frame
+	resume    chan struct{}
+	variables []any
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:          bufio.NewReader(in),
+		out:         out,
+		breakpoints: map[int]bool{},
+		resume:      make(chan struct{}),
+	}
+}
+
+// Serve handles requests until the client disconnects or in is exhausted, a
+// running script is stopped before it returns.
+func (s *Server) Serve() error {
+	defer s.stop()
+	for {
+		req, err := readRequest(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		body, err := s.handle(req)
+		res := response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+		if err != nil {
+			res.Message = err.Error()
+		}
+		if err := s.send(&res); err != nil {
+			return err
+		}
+
+		switch req.Command {
+		case "initialize":
+			if err := s.event("initialized", nil); err != nil {
+				return err
+			}
+		case "launch", "configurationDone":
+			if res.Success {
+				s.start()
+			}
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+func (s *Server) handle(req request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return Capabilities{SupportsConfigurationDoneRequest: true, SupportsTerminateRequest: true}, nil
+	case "launch":
+		var args LaunchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args), nil
+	case "configurationDone":
+		s.configured = true
+		return nil, nil
+	case "threads":
+		return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+	case "stackTrace":
+		return s.stackTrace(), nil
+	case "scopes":
+		var args ScopesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args)
+	case "variables":
+		var args VariablesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variablesOf(args)
+	case "continue":
+		return nil, s.continueWith(modeRun)
+	case "next":
+		return nil, s.continueWith(modeStepOver)
+	case "stepIn":
+		return nil, s.continueWith(modeStepIn)
+	case "stepOut":
+		return nil, s.continueWith(modeStepOut)
+	case "pause":
+		s.mu.Lock()
+		s.pause = true
+		s.mu.Unlock()
+		return nil, nil
+	case "terminate", "disconnect":
+		s.stop()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported command %s", req.Command)
+}
+
+// launch compiles the program, it starts running once the client is done
+// configuring breakpoints.
+func (s *Server) launch(args LaunchArguments) error {
+	if s.launched {
+		return errors.New("a program is already launched")
+	}
+	source, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+
+	scanner := lexer.NewScanner(strings.NewReader(string(source)), io.Discard)
+	parser := ast.NewParser(scanner.ScanTokens(), io.Discard)
+	stmts, hasError := parser.Parse()
+	errs := append(scanner.Errors(), parser.Errors()...)
+	if len(errs) == 0 && !hasError {
+		resolver := resolver.NewResolver(io.Discard)
+		resolver.Resolve(stmts)
+		errs = resolver.Errors()
+	}
+	if len(errs) > 0 {
+		var message strings.Builder
+		printer := diagnostic.NewPrinter(&message, args.Program, string(source))
+		for _, err := range errs {
+			var d diagnostic.Diagnostic
+			if errors.As(err, &d) {
+				printer.Print(d)
+			} else {
+				fmt.Fprintln(&message, err)
+			}
+		}
+		return errors.New(strings.TrimSpace(message.String()))
+	}
+
+	s.program, s.stmts, s.stopOnEntry, s.launched = args.Program, stmts, args.StopOnEntry, true
+	s.stmtLines = map[int]bool{}
+	collectLines(stmts, s.stmtLines)
+	return nil
+}
+
+// collectLines records the lines a statement starts on, breakpoints are only
+// verified on those.
+func collectLines(stmts []ast.Stmt, lines map[int]bool) {
+	for _, stmt := range stmts {
+		lines[ast.StmtStart(stmt).LineNumber] = true
+		switch s := stmt.(type) {
+		case ast.BlockStmt:
+			collectLines(s.Statements, lines)
+		case ast.IfStmt:
+			collectExprLines(s.Condition, lines)
+			collectLines(s.ThenStmt.Statements, lines)
+			switch elseStmt := s.ElseStmt.(type) {
+			case ast.BlockStmt:
+				collectLines(elseStmt.Statements, lines)
+			case ast.IfStmt:
+				collectLines([]ast.Stmt{elseStmt}, lines)
+			}
+		case ast.WhileStmt:
+			collectExprLines(s.Condition, lines)
+			collectLines(s.Stmt.Statements, lines)
+		case ast.ForStmt:
+			if s.Condition != nil {
+				collectExprLines(s.Condition, lines)
+			}
+			collectLines(s.Body.Statements, lines)
+		case ast.ForInStmt:
+			collectExprLines(s.Iterable, lines)
+			collectLines(s.Body.Statements, lines)
+		case ast.FuncStmt:
+			collectLines(s.Body, lines)
+		case ast.ClassStmt:
+			for _, method := range s.Methods {
+				collectLines(method.Body, lines)
+			}
+		case ast.PrintStmt:
+			collectExprLines(s.Expression, lines)
+		case ast.ExprStmt:
+			collectExprLines(s.Expression, lines)
+		case ast.VarStmt:
+			collectExprLines(s.Expression, lines)
+		case ast.AssignStmt:
+			collectExprLines(s.Expression, lines)
+		case ast.IndexAssignStmt:
+			collectExprLines(s.Object, lines)
+			collectExprLines(s.Index, lines)
+			collectExprLines(s.Expression, lines)
+		case ast.PropertyAssignStmt:
+			collectExprLines(s.Object, lines)
+			collectExprLines(s.Expression, lines)
+		case ast.ReturnStmt:
+			if s.Value != nil {
+				collectExprLines(s.Value, lines)
+			}
+		case ast.ThrowStmt:
+			collectExprLines(s.Value, lines)
+		case ast.TryStmt:
+			collectLines(s.Body.Statements, lines)
+			if s.Catch != nil {
+				collectLines(s.Catch.Statements, lines)
+			}
+			if s.Finally != nil {
+				collectLines(s.Finally.Statements, lines)
+			}
+		}
+	}
+}
+
+// collectExprLines records the lines of the statements in the bodies of the
+// anonymous functions in expr.
+func collectExprLines(expr ast.Expr, lines map[int]bool) {
+	switch e := expr.(type) {
+	case ast.FuncExpr:
+		collectLines(e.Declaration.Body, lines)
+	case ast.BinaryExpr:
+		collectExprLines(e.Left, lines)
+		collectExprLines(e.Right, lines)
+	case ast.LogicalExpr:
+		collectExprLines(e.Left, lines)
+		collectExprLines(e.Right, lines)
+	case ast.UnaryExpr:
+		collectExprLines(e.Right, lines)
+	case ast.GroupExpr:
+		collectExprLines(e.Expression, lines)
+	case ast.CallExpr:
+		collectExprLines(e.Callee, lines)
+		for _, argument := range e.Arguments {
+			collectExprLines(argument, lines)
+		}
+	case ast.ListExpr:
+		for _, element := range e.Elements {
+			collectExprLines(element, lines)
+		}
+	case ast.IndexExpr:
+		collectExprLines(e.Object, lines)
+		collectExprLines(e.Index, lines)
+	case ast.PropertyExpr:
+		collectExprLines(e.Object, lines)
+	case ast.TemplateExpr:
+		for _, value := range e.Values {
+			collectExprLines(value, lines)
+		}
+	case ast.MapExpr:
+		for i := range e.Keys {
+			collectExprLines(e.Keys[i], lines)
+			collectExprLines(e.Values[i], lines)
+		}
+	}
+}
+
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) SetBreakpointsResponseBody {
+	breakpoints := map[int]bool{}
+	body := SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}
+	for _, requested := range args.Breakpoints {
+		breakpoint := Breakpoint{Line: requested.Line, Verified: s.stmtLines == nil || s.stmtLines[requested.Line]}
+		if breakpoint.Verified {
+			breakpoints[requested.Line] = true
+		} else {
+			breakpoint.Message = "no statement starts on this line"
+		}
+		body.Breakpoints = append(body.Breakpoints, breakpoint)
+	}
+
+	s.mu.Lock()
+	s.breakpoints = breakpoints
+	s.mu.Unlock()
+	return body
+}
+
+// start runs the launched program once it is configured.
+func (s *Server) start() {
+	if !s.launched || !s.configured || s.done != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel, s.done = cancel, make(chan struct{})
+
+	interp := interpreter.NewInterpreter(&output{s, "stdout"}, &output{s, "stderr"})
+	interp.SetHook(s.hook)
+	s.mu.Lock()
+	if s.stopOnEntry {
+		s.mode = modeStepIn
+	}
+	s.mu.Unlock()
+
+	go func() {
+		defer close(s.done)
+		exitCode := 0
+		if err := interp.InterpretContext(ctx, s.stmts); err != nil && ctx.Err() == nil {
+			// the status the command line exits with on a runtime error
+			exitCode = 70
+			message := err.Error()
+			var runtimeErr interface{ Diagnostic() diagnostic.Diagnostic }
+			if errors.As(err, &runtimeErr) {
+				message = s.program + ":" + runtimeErr.Diagnostic().Error()
+			}
+			s.event("output", OutputEventBody{Category: "stderr", Output: message + "\n"})
+		}
+		s.event("exited", ExitedEventBody{ExitCode: exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+// stop cancels a running script and waits for it to unwind.
+func (s *Server) stop() {
+	if s.done == nil {
+		return
+	}
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+	s.cancel()
+	s.continueWith(modeRun)
+	<-s.done
+}
+
+// hook runs on the script goroutine before every statement and blocks while
+// the script is stopped.
+func (s *Server) hook(stmt ast.Stmt, frames []interpreter.Frame) {
+	s.mu.Lock()
+	if s.globals == nil {
+		s.globals = frames[0].Env
+	}
+	depth := len(frames)
+	var reason string
+	switch {
+	case s.stopping:
+	case s.pause:
+		reason = "pause"
+	case s.mode == modeStepIn && s.stopOnEntry:
+		reason = "entry"
+	case s.mode == modeStepIn,
+		s.mode == modeStepOver && depth <= s.stepDepth,
+		s.mode == modeStepOut && depth < s.stepDepth:
+		reason = "step"
+	case s.breakpoints[ast.StmtStart(stmt).LineNumber]:
+		reason = "breakpoint"
+	}
+	if reason == "" {
+		s.mu.Unlock()
+		return
+	}
+
+	s.stopOnEntry, s.pause = false, false
+	s.frames = make([]interpreter.Frame, depth)
+	for i, frame := range frames {
+		s.frames[depth-1-i] = frame
+	}
+	s.variables = nil
+	s.mu.Unlock()
+
+	s.event("stopped", StoppedEventBody{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+	<-s.resume
+}
+
+// continueWith resumes a stopped script in the given mode, it does nothing
+// when the script is running.
+func (s *Server) continueWith(mode stepMode) error {
+	s.mu.Lock()
+	if s.frames == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mode, s.stepDepth = mode, len(s.frames)
+	s.frames, s.variables = nil, nil
+	s.mu.Unlock()
+	s.resume <- struct{}{}
+	return nil
+}
+
+func (s *Server) stackTrace() StackTraceResponseBody {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body := StackTraceResponseBody{StackFrames: []StackFrame{}, TotalFrames: len(s.frames)}
+	for i, frame := range s.frames {
+		name := frame.Name
+		if name == "" {
+			name = "<skrip>"
+		}
+		body.StackFrames = append(body.StackFrames, StackFrame{
+			ID:     i + 1,
+			Name:   name,
+			Source: Source{Name: filepath.Base(s.program), Path: s.program},
+			Line:   frame.Line(),
+			Column: ast.StmtStart(frame.Stmt).Column,
+		})
+	}
+	return body
+}
+
+// scope is what a variables reference of a scope points at, locals are every
+// environment from env up to the global one.
+type scope struct {
+	env    *environment.Environment
+	global bool
+}
+
+func (s *Server) scopes(args ScopesArguments) (ScopesResponseBody, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if args.FrameID < 1 || args.FrameID > len(s.frames) {
+		return ScopesResponseBody{}, fmt.Errorf("unknown frame %d", args.FrameID)
+	}
+
+	env := s.frames[args.FrameID-1].Env
+	body := ScopesResponseBody{Scopes: []Scope{}}
+	if env != s.globals {
+		body.Scopes = append(body.Scopes, Scope{Name: "Lokal", VariablesReference: s.reference(scope{env: env})})
+	}
+	body.Scopes = append(body.Scopes, Scope{Name: "Global", VariablesReference: s.reference(scope{env: s.globals, global: true})})
+	return body, nil
+}
+
+// reference hands out a variables reference for target, references are only
+// valid until the script resumes.
+func (s *Server) reference(target any) int {
+	s.variables = append(s.variables, target)
+	return len(s.variables)
+}
+
+func (s *Server) variablesOf(args VariablesArguments) (VariablesResponseBody, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if args.VariablesReference < 1 || args.VariablesReference > len(s.variables) {
+		return VariablesResponseBody{}, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+
+	body := VariablesResponseBody{Variables: []Variable{}}
+	switch target := s.variables[args.VariablesReference-1].(type) {
+	case scope:
+		values := map[string]any{}
+		for env := target.env; env != nil; env = env.Encloser() {
+			if env == s.globals && !target.global {
+				break
+			}
+			for name, value := range env.Values() {
+				if _, shadowed := values[name]; !shadowed {
+					values[name] = value
+				}
+			}
+		}
+		names := make([]string, 0, len(values))
+		for name, value := range values {
+			if _, ok := value.(*interpreter.NativeFunction); !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			body.Variables = append(body.Variables, s.variable(name, values[name]))
+		}
+	case *interpreter.List:
+		for i, element := range target.Elements {
+			body.Variables = append(body.Variables, s.variable(strconv.Itoa(i), element))
+		}
+	case *interpreter.Map:
+		for _, key := range target.Keys() {
+			value, _ := target.Get(key)
+			body.Variables = append(body.Variables, s.variable(interpreter.Inspect(key), value))
+		}
+	case *interpreter.Instance:
+		for _, name := range target.Fields() {
+			value, _ := target.Get(name)
+			body.Variables = append(body.Variables, s.variable(name, value))
+		}
+	}
+	return body, nil
+}
+
+func (s *Server) variable(name string, value any) Variable {
+	variable := Variable{Name: name, Value: interpreter.Inspect(value), Type: interpreter.TypeName(value)}
+	switch v := value.(type) {
+	case *interpreter.List:
+		if len(v.Elements) > 0 {
+			variable.VariablesReference = s.reference(v)
+		}
+	case *interpreter.Map:
+		if v.Len() > 0 {
+			variable.VariablesReference = s.reference(v)
+		}
+	case *interpreter.Instance:
+		if len(v.Fields()) > 0 {
+			variable.VariablesReference = s.reference(v)
+		}
+	}
+	return variable
+}
+
+func (s *Server) send(msg any) error {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	s.seq++
+	switch m := msg.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+	return writeMessage(s.out, msg)
+}
+
+func (s *Server) event(name string, body any) error {
+	return s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// output forwards what the script writes as output events.
+type output struct {
+	server   *Server
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if err := o.server.event("output", OutputEventBody{Category: o.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,390 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// incoming is any message the server sends.
+type incoming struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client drives a Server over pipes the way an editor does, events received
+// while waiting for a response are queued for expectEvent.
+type client struct {
+	t       *testing.T
+	in      *io.PipeWriter
+	out     *bufio.Reader
+	seq     int
+	pending []incoming
+	done    chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, in: clientOut, out: bufio.NewReader(clientIn), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+func (c *client) receive() incoming {
+	c.t.Helper()
+	header, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	length, _ := strconv.Atoi(header.Get("Content-Length"))
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.out, body); err != nil {
+		c.t.Fatal(err)
+	}
+	var msg incoming
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// request sends a request and decodes the body of its response into result.
+func (c *client) request(command string, arguments any, result any) incoming {
+	c.t.Helper()
+	c.seq++
+	body, _ := json.Marshal(arguments)
+	if err := writeMessage(c.in, request{Seq: c.seq, Type: "request", Command: command, Arguments: body}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.receive()
+		if msg.Type == "event" {
+			c.pending = append(c.pending, msg)
+			continue
+		}
+		if msg.RequestSeq != c.seq || msg.Command != command {
+			c.t.Fatalf("expected a response to %s while actual is %+v", command, msg)
+		}
+		if result != nil && msg.Success {
+			if err := json.Unmarshal(msg.Body, result); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return msg
+	}
+}
+
+func (c *client) mustRequest(command string, arguments any, result any) {
+	c.t.Helper()
+	if res := c.request(command, arguments, result); !res.Success {
+		c.t.Fatalf("%s failed: %s", command, res.Message)
+	}
+}
+
+// expectEvent skips events until one named name arrives and decodes its body.
+func (c *client) expectEvent(name string, body any) {
+	c.t.Helper()
+	for {
+		var msg incoming
+		if len(c.pending) > 0 {
+			msg, c.pending = c.pending[0], c.pending[1:]
+		} else {
+			msg = c.receive()
+		}
+		if msg.Type == "event" && msg.Event == name {
+			if body != nil {
+				if err := json.Unmarshal(msg.Body, body); err != nil {
+					c.t.Fatal(err)
+				}
+			}
+			return
+		}
+		if msg.Type != "event" {
+			c.t.Fatalf("expected event %s while actual is %+v", name, msg)
+		}
+	}
+}
+
+func (c *client) expectStopped(reason string, line int) []StackFrame {
+	c.t.Helper()
+	var stopped StoppedEventBody
+	c.expectEvent("stopped", &stopped)
+	if stopped.Reason != reason {
+		c.t.Fatalf("expected to stop because of %s while actual is %s", reason, stopped.Reason)
+	}
+	var trace StackTraceResponseBody
+	c.mustRequest("stackTrace", map[string]any{"threadId": threadID}, &trace)
+	if trace.StackFrames[0].Line != line {
+		c.t.Fatalf("expected to stop on line %d while actual is %+v", line, trace.StackFrames)
+	}
+	return trace.StackFrames
+}
+
+const script = `misal daftar = [1, 2];
+fungsi tambah(a, b) {
+    misal jumlah = a + b;
+    balikin jumlah;
+}
+misal hasil = tambah(1, 2);
+cetak hasil;
+cetak tambah(hasil, 4);
+`
+
+// launch starts script with breakpoints on lines.
+func launch(t *testing.T, c *client, source string, stopOnEntry bool, lines ...int) {
+	t.Helper()
+	program := filepath.Join(t.TempDir(), "skrip.indos")
+	if err := os.WriteFile(program, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mustRequest("initialize", map[string]any{"adapterID": "indoscript"}, nil)
+	c.expectEvent("initialized", nil)
+	c.mustRequest("launch", LaunchArguments{Program: program, StopOnEntry: stopOnEntry}, nil)
+	breakpoints := []SourceBreakpoint{}
+	for _, line := range lines {
+		breakpoints = append(breakpoints, SourceBreakpoint{Line: line})
+	}
+	var body SetBreakpointsResponseBody
+	c.mustRequest("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: program}, Breakpoints: breakpoints}, &body)
+	c.mustRequest("configurationDone", nil, nil)
+}
+
+func TestBreakpointsAndVariables(t *testing.T) {
+	c := newClient(t)
+	launch(t, c, script, false, 3, 7)
+
+	frames := c.expectStopped("breakpoint", 3)
+	if len(frames) != 2 || frames[0].Name != "tambah" || frames[1].Name != "<skrip>" || frames[1].Line != 6 {
+		t.Fatalf("unexpected frames %+v", frames)
+	}
+
+	var scopes ScopesResponseBody
+	c.mustRequest("scopes", ScopesArguments{FrameID: frames[0].ID}, &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Lokal" || scopes.Scopes[1].Name != "Global" {
+		t.Fatalf("unexpected scopes %+v", scopes)
+	}
+	var locals VariablesResponseBody
+	c.mustRequest("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &locals)
+	expectedLocals := []Variable{
+		{Name: "a", Value: "1", Type: "angka"},
+		{Name: "b", Value: "2", Type: "angka"},
+	}
+	if len(locals.Variables) != len(expectedLocals) {
+		t.Fatalf("expected locals %+v while actual is %+v", expectedLocals, locals.Variables)
+	}
+	for i := range expectedLocals {
+		if locals.Variables[i] != expectedLocals[i] {
+			t.Fatalf("expected locals %+v while actual is %+v", expectedLocals, locals.Variables)
+		}
+	}
+
+	var globals VariablesResponseBody
+	c.mustRequest("variables", VariablesArguments{VariablesReference: scopes.Scopes[1].VariablesReference}, &globals)
+	if len(globals.Variables) != 2 || globals.Variables[0].Name != "daftar" || globals.Variables[1].Name != "tambah" {
+		t.Fatalf("unexpected globals %+v", globals.Variables)
+	}
+	list := globals.Variables[0]
+	if list.Value != "[1, 2]" || list.Type != "daftar" || list.VariablesReference == 0 {
+		t.Fatalf("unexpected list variable %+v", list)
+	}
+	var elements VariablesResponseBody
+	c.mustRequest("variables", VariablesArguments{VariablesReference: list.VariablesReference}, &elements)
+	if len(elements.Variables) != 2 || elements.Variables[1].Name != "1" || elements.Variables[1].Value != "2" {
+		t.Fatalf("unexpected list elements %+v", elements.Variables)
+	}
+
+	c.mustRequest("continue", map[string]any{"threadId": threadID}, nil)
+	c.expectStopped("breakpoint", 7)
+	c.mustRequest("continue", map[string]any{"threadId": threadID}, nil)
+	c.expectStopped("breakpoint", 3)
+	c.mustRequest("continue", map[string]any{"threadId": threadID}, nil)
+
+	var output OutputEventBody
+	c.expectEvent("output", &output)
+	if output.Output != "7\n" || output.Category != "stdout" {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	var exited ExitedEventBody
+	c.expectEvent("exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Fatalf("expected exit code 0 while actual is %d", exited.ExitCode)
+	}
+	c.expectEvent("terminated", nil)
+	c.mustRequest("disconnect", nil, nil)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStepping(t *testing.T) {
+	c := newClient(t)
+	launch(t, c, script, true)
+
+	thread := map[string]any{"threadId": threadID}
+	c.expectStopped("entry", 1)
+	c.mustRequest("next", thread, nil)
+	c.expectStopped("step", 2)
+	c.mustRequest("next", thread, nil)
+	c.expectStopped("step", 6)
+	c.mustRequest("stepIn", thread, nil)
+	c.expectStopped("step", 3)
+	c.mustRequest("stepOut", thread, nil)
+	c.expectStopped("step", 7)
+	c.mustRequest("next", thread, nil)
+	c.expectStopped("step", 8)
+	c.mustRequest("next", thread, nil)
+	c.expectEvent("terminated", nil)
+}
+
+func TestBreakpointVerification(t *testing.T) {
+	c := newClient(t)
+	program := filepath.Join(t.TempDir(), "skrip.indos")
+	os.WriteFile(program, []byte(script), 0o644)
+	c.mustRequest("initialize", nil, nil)
+	c.expectEvent("initialized", nil)
+	c.mustRequest("launch", LaunchArguments{Program: program}, nil)
+
+	var body SetBreakpointsResponseBody
+	c.mustRequest("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: program},
+		Breakpoints: []SourceBreakpoint{{Line: 3}, {Line: 5}},
+	}, &body)
+	if len(body.Breakpoints) != 2 || !body.Breakpoints[0].Verified || body.Breakpoints[1].Verified {
+		t.Fatalf("unexpected breakpoints %+v", body.Breakpoints)
+	}
+}
+
+func TestBreakpointsInAnonymousFunctions(t *testing.T) {
+	c := newClient(t)
+	program := filepath.Join(t.TempDir(), "skrip.indos")
+	os.WriteFile(program, []byte(`misal fs = [fungsi () => 0];
+jika (fungsi () {
+    balikin benar;
+})() {}
+selama (fungsi () {
+    balikin salah;
+})() {}
+untuk misal i = 0; i < (fungsi () {
+    balikin 1;
+})(); i = i + 1 {}
+cetak fs[(fungsi () {
+    balikin 0;
+})()]();
+`), 0o644)
+	c.mustRequest("initialize", nil, nil)
+	c.expectEvent("initialized", nil)
+	c.mustRequest("launch", LaunchArguments{Program: program}, nil)
+
+	var body SetBreakpointsResponseBody
+	c.mustRequest("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: program},
+		Breakpoints: []SourceBreakpoint{{Line: 3}, {Line: 6}, {Line: 9}, {Line: 12}},
+	}, &body)
+	for _, breakpoint := range body.Breakpoints {
+		if !breakpoint.Verified {
+			t.Fatalf("expected every breakpoint to be verified, got %+v", body.Breakpoints)
+		}
+	}
+}
+
+func TestInstanceVariables(t *testing.T) {
+	c := newClient(t)
+	launch(t, c, `kelas Titik {
+    inisialisasi(x) {
+        ini.x = x;
+        ini.label = [x];
+    }
+}
+misal t = Titik(3);
+cetak t;
+`, false, 8)
+
+	frames := c.expectStopped("breakpoint", 8)
+	var scopes ScopesResponseBody
+	c.mustRequest("scopes", ScopesArguments{FrameID: frames[0].ID}, &scopes)
+	var globals VariablesResponseBody
+	c.mustRequest("variables", VariablesArguments{VariablesReference: scopes.Scopes[len(scopes.Scopes)-1].VariablesReference}, &globals)
+	var instance Variable
+	for _, variable := range globals.Variables {
+		if variable.Name == "t" {
+			instance = variable
+		}
+	}
+	if instance.Type != "objek" || instance.VariablesReference == 0 {
+		t.Fatalf("unexpected objek variable %+v", instance)
+	}
+
+	var fields VariablesResponseBody
+	c.mustRequest("variables", VariablesArguments{VariablesReference: instance.VariablesReference}, &fields)
+	if len(fields.Variables) != 2 {
+		t.Fatalf("expected fields x and label while actual is %+v", fields.Variables)
+	}
+	if x := fields.Variables[0]; x != (Variable{Name: "x", Value: "3", Type: "angka"}) {
+		t.Fatalf("unexpected field %+v", x)
+	}
+	if label := fields.Variables[1]; label.Name != "label" || label.Value != "[3]" || label.VariablesReference == 0 {
+		t.Fatalf("unexpected field %+v", label)
+	}
+	c.mustRequest("disconnect", nil, nil)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDisconnectWhileStopped(t *testing.T) {
+	c := newClient(t)
+	launch(t, c, "selama benar {\n    misal a = 1;\n}\n", false, 2)
+	c.expectStopped("breakpoint", 2)
+	c.mustRequest("disconnect", nil, nil)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLaunchErrors(t *testing.T) {
+	c := newClient(t)
+	program := filepath.Join(t.TempDir(), "skrip.indos")
+	os.WriteFile(program, []byte("misal a = ;\n"), 0o644)
+	c.mustRequest("initialize", nil, nil)
+	c.expectEvent("initialized", nil)
+	res := c.request("launch", LaunchArguments{Program: program}, nil)
+	if res.Success || res.Message == "" {
+		t.Fatalf("expected launch to fail while actual is %+v", res)
+	}
+	if res := c.request("evaluate", nil, nil); res.Success {
+		t.Fatalf("expected an unsupported command to fail")
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	c := newClient(t)
+	launch(t, c, "cetak 1 - \"a\";\n", false)
+	var output OutputEventBody
+	c.expectEvent("output", &output)
+	if output.Category != "stderr" || output.Output == "" {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	var exited ExitedEventBody
+	c.expectEvent("exited", &exited)
+	if exited.ExitCode != 70 {
+		t.Fatalf("expected exit code 70 while actual is %d", exited.ExitCode)
+	}
+}
@@ -0,0 +1,68 @@
+// Package diagnostic describes problems found in a script, whether they are
+// reported while scanning, parsing, resolving or running it, and renders them
+// together with the source they point at.
+package diagnostic
+
+import "fmt"
+
+type Severity uint8
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "unknown"
+}
+
+// Codes tell which phase reported a diagnostic.
+const (
+	CodeLexical = "lexical"
+	CodeSyntax  = "syntax"
+	CodeResolve = "resolve"
+	CodeCompile = "compile"
+	CodeRuntime = "runtime"
+
+	// CodeUnterminatedRawString is the lexical error of a source ending
+	// inside a raw string, which a REPL continues on the next line
+	CodeUnterminatedRawString = "unterminated-raw-string"
+)
+
+// Position is a location in the source. Line and Column are 1-based, Column
+// counts runes, Offset is the 0-based byte offset.
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+// Span is the half open range of source between Start and End. An empty span
+// points at the position right before Start, such as the end of the file.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Span     Span
+	Code     string
+	Message  string
+}
+
+func NewError(span Span, code, message string) Diagnostic {
+	return Diagnostic{Severity: SeverityError, Span: span, Code: code, Message: message}
+}
+
+// Error formats the diagnostic on a single line as line:column: severity:
+// message, it is what is written when the source is not at hand.
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Span.Start.Line, d.Span.Start.Column, d.Severity, d.Message)
+}
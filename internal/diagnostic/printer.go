@@ -0,0 +1,82 @@
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Printer renders diagnostics with the line of source they point at and a
+// caret underline below the offending span:
+//
+//	error[syntax]: expect ';' after statement
+//	 --> halo.indos:1:14
+//	  |
+//	1 | cetak "halo"
+//	  |              ^
+type Printer struct {
+	w        io.Writer
+	filename string
+	lines    []string
+}
+
+func NewPrinter(w io.Writer, filename, source string) *Printer {
+	return &Printer{
+		w:        w,
+		filename: filename,
+		lines:    strings.Split(source, "\n"),
+	}
+}
+
+func (p *Printer) Print(d Diagnostic) {
+	start := d.Span.Start
+	fmt.Fprintf(p.w, "%s[%s]: %s\n", d.Severity, d.Code, d.Message)
+	if start.Line < 1 || start.Line > len(p.lines) {
+		if p.filename != "" {
+			fmt.Fprintf(p.w, " --> %s\n", p.filename)
+		}
+		return
+	}
+
+	fmt.Fprintf(p.w, " --> %s:%d:%d\n", p.filename, start.Line, start.Column)
+	line := strings.TrimSuffix(p.lines[start.Line-1], "\r")
+	gutter := strings.Repeat(" ", len(strconv.Itoa(start.Line)))
+	fmt.Fprintf(p.w, "%s |\n", gutter)
+	fmt.Fprintf(p.w, "%d | %s\n", start.Line, line)
+	fmt.Fprintf(p.w, "%s | %s%s\n", gutter, p.indent(line, start.Column), p.underline(d.Span, line))
+}
+
+// indent reproduces the whitespace before column so tabs in the source line
+// keep the caret aligned.
+func (p *Printer) indent(line string, column int) string {
+	var b strings.Builder
+	for i, r := range []rune(line) {
+		if i >= column-1 {
+			break
+		}
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	for i := utf8.RuneCountInString(line); i < column-1; i++ {
+		b.WriteRune(' ')
+	}
+	return b.String()
+}
+
+// underline covers the span up to the end of its first line, an empty span
+// still gets a single caret.
+func (p *Printer) underline(span Span, line string) string {
+	width := span.End.Column - span.Start.Column
+	if span.End.Line != span.Start.Line {
+		width = utf8.RuneCountInString(line) - span.Start.Column + 1
+	}
+	if width < 1 {
+		width = 1
+	}
+	return strings.Repeat("^", width)
+}
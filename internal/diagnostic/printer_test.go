@@ -0,0 +1,73 @@
+package diagnostic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	source := "misal a = 1;\n\tcetak a - \"b\";\n"
+	testcases := []struct {
+		name       string
+		diagnostic Diagnostic
+		expected   string
+	}{
+		{
+			name: "token",
+			diagnostic: NewError(Span{
+				Start: Position{Line: 2, Column: 10, Offset: 22},
+				End:   Position{Line: 2, Column: 13, Offset: 25},
+			}, CodeRuntime, "operands must be numbers"),
+			expected: "error[runtime]: operands must be numbers\n" +
+				" --> skrip.indos:2:10\n" +
+				"  |\n" +
+				"2 | \tcetak a - \"b\";\n" +
+				"  | \t        ^^^\n",
+		},
+		{
+			name: "empty span",
+			diagnostic: NewError(Span{
+				Start: Position{Line: 1, Column: 13, Offset: 12},
+				End:   Position{Line: 1, Column: 13, Offset: 12},
+			}, CodeSyntax, "expect expression"),
+			expected: "error[syntax]: expect expression\n" +
+				" --> skrip.indos:1:13\n" +
+				"  |\n" +
+				"1 | misal a = 1;\n" +
+				"  |             ^\n",
+		},
+		{
+			name: "spans lines",
+			diagnostic: NewError(Span{
+				Start: Position{Line: 1, Column: 7, Offset: 6},
+				End:   Position{Line: 2, Column: 3, Offset: 15},
+			}, CodeLexical, "unterminated string"),
+			expected: "error[lexical]: unterminated string\n" +
+				" --> skrip.indos:1:7\n" +
+				"  |\n" +
+				"1 | misal a = 1;\n" +
+				"  |       ^^^^^^\n",
+		},
+		{
+			name:       "outside source",
+			diagnostic: NewError(Span{}, CodeRuntime, "stack overflow"),
+			expected:   "error[runtime]: stack overflow\n --> skrip.indos\n",
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			out := new(strings.Builder)
+			NewPrinter(out, "skrip.indos", source).Print(testcase.diagnostic)
+			if out.String() != testcase.expected {
+				t.Fatalf("expected\n%s\nwhile actual is\n%s", testcase.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	d := NewError(Span{Start: Position{Line: 3, Column: 4}}, CodeSyntax, "expect ';' after statement")
+	if expected := "3:4: error: expect ';' after statement"; d.Error() != expected {
+		t.Fatalf("expected %q while actual is %q", expected, d.Error())
+	}
+}
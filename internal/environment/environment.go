@@ -16,31 +16,77 @@ func (e *Environment) Define(identifier ast.Token, value any) {
 	e.values[identifier.Lexeme] = value
 }
 
-func (e *Environment) Assign(identifier ast.Token, value any) {
-	if _, ok := e.values[identifier.Lexeme]; !ok {
-		if e.encloser == nil {
-			e.error(identifier, fmt.Sprintf("Undefined variable %s\n", identifier.Lexeme))
+// Assign sets an existing variable, searching the encloser chain outwards. It
+// fails when no environment defines the variable.
+func (e *Environment) Assign(identifier ast.Token, value any) error {
+	for env := e; env != nil; env = env.encloser {
+		if _, ok := env.values[identifier.Lexeme]; ok {
+			env.values[identifier.Lexeme] = value
+			return nil
 		}
-		e.encloser.Assign(identifier, value)
 	}
+	return undefined(identifier)
+}
 
-	e.values[identifier.Lexeme] = value
+// Get reads a variable, searching the encloser chain outwards. It fails when
+// no environment defines the variable.
+func (e *Environment) Get(identifier ast.Token) (any, error) {
+	for env := e; env != nil; env = env.encloser {
+		if value, ok := env.values[identifier.Lexeme]; ok {
+			return value, nil
+		}
+	}
+	return nil, undefined(identifier)
 }
 
-func (e *Environment) Get(identifier ast.Token) any {
-	value, ok := e.values[identifier.Lexeme]
+// GetAt reads a variable from the environment distance hops up the encloser
+// chain, as computed by the resolver.
+func (e *Environment) GetAt(distance int, identifier ast.Token) (any, error) {
+	value, ok := e.ancestor(distance).values[identifier.Lexeme]
 	if !ok {
-		if e.encloser == nil {
-			e.error(identifier, fmt.Sprintf("Undefined variable %s\n", identifier.Lexeme))
-		}
-		return e.encloser.Get(identifier)
+		return nil, undefined(identifier)
+	}
+	return value, nil
+}
+
+// AssignAt writes a variable in the environment distance hops up the encloser
+// chain, as computed by the resolver.
+func (e *Environment) AssignAt(distance int, identifier ast.Token, value any) {
+	e.ancestor(distance).values[identifier.Lexeme] = value
+}
+
+// Encloser returns the environment this one is nested in, nil for the global
+// environment.
+func (e *Environment) Encloser() *Environment {
+	return e.encloser
+}
+
+// Values returns a copy of the variables defined directly in this
+// environment, debuggers use it to show the variables in scope.
+func (e *Environment) Values() map[string]any {
+	values := make(map[string]any, len(e.values))
+	for name, value := range e.values {
+		values[name] = value
+	}
+	return values
+}
+
+// Copy returns a new environment with the same encloser and its own copy of
+// the variables, loops use it to give every iteration fresh variables.
+func (e *Environment) Copy() *Environment {
+	return &Environment{values: e.Values(), encloser: e.encloser}
+}
+
+func (e *Environment) ancestor(distance int) *Environment {
+	env := e
+	for i := 0; i < distance; i++ {
+		env = env.encloser
 	}
-	return value
+	return env
 }
 
-func (e *Environment) error(token ast.Token, message string) {
-	err := errors.NewRuntimeError(token, message)
-	panic(err)
+func undefined(identifier ast.Token) error {
+	return errors.NewRuntimeError(identifier, fmt.Sprintf("Undefined variable %s", identifier.Lexeme))
 }
 
 func NewEnvironment(encloser *Environment) *Environment {
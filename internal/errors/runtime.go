@@ -4,17 +4,68 @@ import (
 	"fmt"
 
 	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
 )
 
 type RuntimeError struct {
 	message string
 	token   ast.Token
+	trace   []StackFrame
+	// value is what a lempar statement threw, nil for errors raised by the
+	// runtime itself
+	value any
+}
+
+// StackFrame is a call that was in progress when a runtime error happened.
+// Function is empty for the top level of the script. Line is where the frame
+// was when the error happened, the call site of the frame above it or the
+// line of the error for the innermost frame.
+type StackFrame struct {
+	Function string
+	Line     int
 }
 
 func (e RuntimeError) Error() string {
 	return fmt.Sprintf("'%s' - %s", e.token.Lexeme, e.message)
 }
 
+func (e RuntimeError) Token() ast.Token {
+	return e.token
+}
+
+func (e RuntimeError) Message() string {
+	return e.message
+}
+
+// Trace returns the calls that led to the error, the innermost call first. It
+// is nil until the backend running the script attaches it.
+func (e RuntimeError) Trace() []StackFrame {
+	return e.trace
+}
+
+// Value returns the value thrown with lempar, or nil when the runtime raised
+// the error.
+func (e RuntimeError) Value() any {
+	return e.value
+}
+
+// WithTrace returns a copy of the error carrying trace.
+func (e RuntimeError) WithTrace(trace []StackFrame) RuntimeError {
+	e.trace = trace
+	return e
+}
+
+// Diagnostic describes the error at the token it was reported at.
+func (e RuntimeError) Diagnostic() diagnostic.Diagnostic {
+	return diagnostic.NewError(e.token.Span(), diagnostic.CodeRuntime, e.message)
+}
+
 func NewRuntimeError(token ast.Token, message string) error {
 	return RuntimeError{token: token, message: message}
 }
+
+// NewThrownError creates the error raised by throwing value at token, message
+// describes value.
+func NewThrownError(token ast.Token, message string, value any) error {
+	return RuntimeError{token: token, message: message, value: value}
+}
@@ -0,0 +1,402 @@
+// Package format prints parsed indoscript programs in the canonical style:
+// four space indentation, one statement per line, single spaces around binary
+// operators and after commas. Comments and single blank lines between
+// statements are carried over from the original token stream.
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aselhid/indoscript/internal/ast"
+)
+
+const indentation = "    "
+
+// Source formats stmts. tokens must be the stream stmts were parsed from,
+// including comments, as returned by Scanner.ScanTokensWithComments.
+func Source(tokens []ast.Token, stmts []ast.Stmt) string {
+	p := &printer{out: &strings.Builder{}, tokens: tokens, printed: map[int]bool{}}
+	end := ast.Token{TokenType: ast.TokenEof, Offset: -1}
+	if len(tokens) > 0 {
+		end = tokens[len(tokens)-1]
+	}
+	if end.TokenType != ast.TokenEof {
+		// there is no EOF to stop at, every remaining comment belongs to the end
+		end = ast.Token{TokenType: ast.TokenEof, Offset: end.Offset + len(end.Lexeme)}
+	}
+	p.stmts(stmts, end)
+
+	out := strings.TrimRight(p.out.String(), "\n")
+	if out == "" {
+		return ""
+	}
+	return out + "\n"
+}
+
+// Expr formats expr the way it is printed inside a statement.
+func Expr(expr ast.Expr) string {
+	p := &printer{out: &strings.Builder{}, printed: map[int]bool{}}
+	return p.expr(expr)
+}
+
+type printer struct {
+	out    *strings.Builder
+	indent int
+
+	tokens []ast.Token
+	// cursor is the index of the first token whose leading comments haven't
+	// been written yet
+	cursor int
+	// printed holds the comments already written as trailing comments
+	printed map[int]bool
+	// first is true until something is written in the current block, blank
+	// lines are never written at the start of a block
+	first bool
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+func (p *printer) writeIndent() {
+	p.write(strings.Repeat(indentation, p.indent))
+}
+
+// stmts writes stmts one per line followed by the comments in front of end,
+// which is the '}' closing the block or EOF.
+func (p *printer) stmts(stmts []ast.Stmt, end ast.Token) {
+	p.first = true
+	for i, stmt := range stmts {
+		start := ast.StmtStart(stmt)
+		next := end
+		if i+1 < len(stmts) {
+			next = ast.StmtStart(stmts[i+1])
+		}
+		p.comments(start)
+		p.blankLine(p.index(start.Offset))
+		p.stmt(stmt, next)
+	}
+	p.comments(end)
+}
+
+// comments writes the comments before token on their own lines.
+func (p *printer) comments(token ast.Token) {
+	index := p.index(token.Offset)
+	for ; p.cursor < index; p.cursor++ {
+		if p.tokens[p.cursor].TokenType != ast.TokenComment || p.printed[p.cursor] {
+			continue
+		}
+		p.blankLine(p.cursor)
+		p.writeIndent()
+		p.write(comment(p.tokens[p.cursor]))
+		p.write("\n")
+		p.first = false
+	}
+}
+
+// blankLine keeps one blank line before the token at index where the source
+// had at least one.
+func (p *printer) blankLine(index int) {
+	if !p.first && index > 0 && index < len(p.tokens) {
+		previous := p.tokens[index-1].Span().End.Line
+		if p.tokens[index].LineNumber-previous > 1 {
+			p.write("\n")
+		}
+	}
+	p.first = false
+}
+
+// trailing writes the comment sharing a line with the last token before next,
+// if there is one.
+func (p *printer) trailing(next ast.Token) {
+	last := p.index(next.Offset) - 1
+	for last >= p.cursor && p.tokens[last].TokenType == ast.TokenComment {
+		last--
+	}
+	if last < 0 || last+1 >= len(p.tokens) {
+		return
+	}
+	candidate := p.tokens[last+1]
+	if candidate.TokenType != ast.TokenComment || p.printed[last+1] ||
+		candidate.LineNumber != p.tokens[last].Span().End.Line {
+		return
+	}
+	p.write(" " + comment(candidate))
+	p.printed[last+1] = true
+}
+
+// inner writes the comments still unwritten inside the statement ending before
+// next as trailing comments. They sat between the lines of an expression that
+// is now printed on one line, keeping them there leaves them describing it.
+func (p *printer) inner(next ast.Token) {
+	last := p.index(next.Offset) - 1
+	for last >= p.cursor && p.tokens[last].TokenType == ast.TokenComment {
+		last--
+	}
+	for i := p.cursor; i < last; i++ {
+		if p.tokens[i].TokenType == ast.TokenComment && !p.printed[i] {
+			p.write(" " + comment(p.tokens[i]))
+			p.printed[i] = true
+		}
+	}
+}
+
+// index returns the index of the first token at or after offset.
+func (p *printer) index(offset int) int {
+	if offset < 0 {
+		return len(p.tokens)
+	}
+	return sort.Search(len(p.tokens), func(i int) bool {
+		return p.tokens[i].Offset >= offset
+	})
+}
+
+// hasComments reports whether an unwritten comment comes before token.
+func (p *printer) hasComments(token ast.Token) bool {
+	for i := p.cursor; i < p.index(token.Offset); i++ {
+		if p.tokens[i].TokenType == ast.TokenComment && !p.printed[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func comment(token ast.Token) string {
+	return strings.TrimRight(token.Lexeme, " \t\r")
+}
+
+// stmt writes stmt on its own line, next is the token following it.
+func (p *printer) stmt(stmt ast.Stmt, next ast.Token) {
+	p.writeIndent()
+	switch s := stmt.(type) {
+	case ast.PrintStmt:
+		p.write("cetak " + p.expr(s.Expression) + ";")
+	case ast.ExprStmt, ast.VarStmt, ast.AssignStmt, ast.IndexAssignStmt, ast.PropertyAssignStmt:
+		p.write(p.simpleStmt(s) + ";")
+	case ast.BlockStmt:
+		p.block(s.Statements, s.RightBrace)
+	case ast.IfStmt:
+		p.ifStmt(s)
+	case ast.WhileStmt:
+		p.label(s.Label)
+		p.write("selama " + p.expr(s.Condition) + " ")
+		p.block(s.Stmt.Statements, s.Stmt.RightBrace)
+	case ast.ForStmt:
+		p.label(s.Label)
+		clauses := p.simpleStmt(s.Initializer) + ";"
+		if s.Condition != nil {
+			clauses += " " + p.expr(s.Condition)
+		}
+		clauses += ";"
+		if s.Increment != nil {
+			clauses += " " + p.simpleStmt(s.Increment)
+		}
+		p.write("untuk " + clauses + " ")
+		p.block(s.Body.Statements, s.Body.RightBrace)
+	case ast.ForInStmt:
+		p.label(s.Label)
+		p.write("untuk " + s.Variable.Lexeme + " dalam " + p.expr(s.Iterable) + " ")
+		p.block(s.Body.Statements, s.Body.RightBrace)
+	case ast.FuncStmt:
+		if s.Keyword.TokenType != ast.TokenIdentifier {
+			// methods are declared by their name alone
+			p.write("fungsi ")
+		}
+		p.write(s.Name.Lexeme + p.parameters(s) + " ")
+		p.block(s.Body, s.RightBrace)
+	case ast.ClassStmt:
+		methods := make([]ast.Stmt, len(s.Methods))
+		for i, method := range s.Methods {
+			methods[i] = method
+		}
+		p.write("kelas " + s.Name.Lexeme + " ")
+		if s.Superclass != nil {
+			p.write("< " + s.Superclass.Identifier.Lexeme + " ")
+		}
+		p.block(methods, s.RightBrace)
+	case ast.ReturnStmt:
+		if s.Value == nil {
+			p.write("balikin;")
+		} else {
+			p.write("balikin " + p.expr(s.Value) + ";")
+		}
+	case ast.BreakStmt:
+		p.write("berhenti" + labelSuffix(s.Label) + ";")
+	case ast.ContinueStmt:
+		p.write("lanjut" + labelSuffix(s.Label) + ";")
+	case ast.ThrowStmt:
+		p.write("lempar " + p.expr(s.Value) + ";")
+	case ast.TryStmt:
+		p.write("coba ")
+		p.block(s.Body.Statements, s.Body.RightBrace)
+		if s.Catch != nil {
+			p.write(" tangkap (" + s.CatchName.Lexeme + ") ")
+			p.block(s.Catch.Statements, s.Catch.RightBrace)
+		}
+		if s.Finally != nil {
+			p.write(" akhirnya ")
+			p.block(s.Finally.Statements, s.Finally.RightBrace)
+		}
+	}
+	p.inner(next)
+	p.trailing(next)
+	p.write("\n")
+}
+
+// simpleStmt formats an expression, declaration or assignment without its
+// ';', as they also appear in the clauses of an untuk loop. An absent clause is
+// empty.
+func (p *printer) simpleStmt(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case ast.VarStmt:
+		return "misal " + s.Identifier.Lexeme + " = " + p.expr(s.Expression)
+	case ast.AssignStmt:
+		return s.Identifier.Lexeme + " = " + p.expr(s.Expression)
+	case ast.IndexAssignStmt:
+		return p.expr(s.Object) + "[" + p.expr(s.Index) + "] = " + p.expr(s.Expression)
+	case ast.PropertyAssignStmt:
+		return p.expr(s.Object) + "." + s.Name.Lexeme + " = " + p.expr(s.Expression)
+	case ast.ExprStmt:
+		return p.expr(s.Expression)
+	}
+	return ""
+}
+
+// ifStmt writes a jika statement, 'lain jika' branches stay on the line of the
+// '}' before them rather than nesting.
+func (p *printer) ifStmt(s ast.IfStmt) {
+	p.write("jika " + p.expr(s.Condition) + " ")
+	p.block(s.ThenStmt.Statements, s.ThenStmt.RightBrace)
+	switch elseStmt := s.ElseStmt.(type) {
+	case ast.BlockStmt:
+		p.write(" lain ")
+		p.block(elseStmt.Statements, elseStmt.RightBrace)
+	case ast.IfStmt:
+		p.write(" lain ")
+		p.ifStmt(elseStmt)
+	}
+}
+
+// label writes the label of a labeled loop.
+func (p *printer) label(label ast.Token) {
+	if label.Lexeme != "" {
+		p.write(label.Lexeme + ": ")
+	}
+}
+
+func labelSuffix(label ast.Token) string {
+	if label.Lexeme == "" {
+		return ""
+	}
+	return " " + label.Lexeme
+}
+
+// block writes a braced block, the opening brace goes on the current line.
+func (p *printer) block(stmts []ast.Stmt, rightBrace ast.Token) {
+	if len(stmts) == 0 && !p.hasComments(rightBrace) {
+		p.write("{}")
+		return
+	}
+
+	p.write("{")
+	first := rightBrace
+	if len(stmts) > 0 {
+		first = ast.StmtStart(stmts[0])
+	}
+	p.trailing(first)
+	p.write("\n")
+
+	p.indent++
+	p.stmts(stmts, rightBrace)
+	p.indent--
+	p.writeIndent()
+	p.write("}")
+}
+
+// parameters formats the parenthesized parameter list of a fungsi.
+func (p *printer) parameters(declaration ast.FuncStmt) string {
+	parameters := make([]string, len(declaration.Parameters))
+	for i, parameter := range declaration.Parameters {
+		parameters[i] = parameter.Lexeme
+		if declaration.Defaults[i] != nil {
+			parameters[i] += " = " + p.expr(declaration.Defaults[i])
+		}
+	}
+	if declaration.Variadic {
+		parameters[len(parameters)-1] = "..." + parameters[len(parameters)-1]
+	}
+	return "(" + strings.Join(parameters, ", ") + ")"
+}
+
+// expr formats expr on a single line, except for the body of an anonymous
+// fungsi which is indented like a block at the current level.
+func (p *printer) expr(expression ast.Expr) string {
+	switch e := expression.(type) {
+	case ast.BinaryExpr:
+		return p.expr(e.Left) + " " + e.Operator.Lexeme + " " + p.expr(e.Right)
+	case ast.LogicalExpr:
+		return p.expr(e.Left) + " " + e.Operator.Lexeme + " " + p.expr(e.Right)
+	case ast.UnaryExpr:
+		return e.Operator.Lexeme + p.expr(e.Right)
+	case ast.PrimaryExpr:
+		return e.Token.Lexeme
+	case ast.GroupExpr:
+		return "(" + p.expr(e.Expression) + ")"
+	case ast.VarExpr:
+		return e.Identifier.Lexeme
+	case ast.CallExpr:
+		arguments := make([]string, len(e.Arguments))
+		for i, argument := range e.Arguments {
+			arguments[i] = p.expr(argument)
+			if e.Names != nil && e.Names[i].Lexeme != "" {
+				arguments[i] = e.Names[i].Lexeme + ": " + arguments[i]
+			}
+		}
+		return p.expr(e.Callee) + "(" + strings.Join(arguments, ", ") + ")"
+	case ast.ListExpr:
+		return "[" + p.exprs(e.Elements) + "]"
+	case ast.IndexExpr:
+		return p.expr(e.Object) + "[" + p.expr(e.Index) + "]"
+	case ast.PropertyExpr:
+		return p.expr(e.Object) + "." + e.Name.Lexeme
+	case ast.ThisExpr:
+		return e.Keyword.Lexeme
+	case ast.SuperExpr:
+		return e.Keyword.Lexeme + "." + e.Method.Lexeme
+	case ast.TemplateExpr:
+		var b strings.Builder
+		for i, value := range e.Values {
+			b.WriteString(e.Segments[i].Lexeme)
+			b.WriteString(p.expr(value))
+		}
+		b.WriteString(e.Segments[len(e.Values)].Lexeme)
+		return b.String()
+	case ast.MapExpr:
+		entries := make([]string, len(e.Keys))
+		for i := range e.Keys {
+			entries[i] = p.expr(e.Keys[i]) + ": " + p.expr(e.Values[i])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case ast.FuncExpr:
+		declaration := e.Declaration
+		if e.Arrow {
+			return "fungsi " + p.parameters(declaration) + " => " + p.expr(declaration.Body[0].(ast.ReturnStmt).Value)
+		}
+		out := p.out
+		p.out = &strings.Builder{}
+		p.block(declaration.Body, declaration.RightBrace)
+		body := p.out.String()
+		p.out = out
+		return "fungsi " + p.parameters(declaration) + " " + body
+	}
+	return ""
+}
+
+func (p *printer) exprs(expressions []ast.Expr) string {
+	formatted := make([]string, len(expressions))
+	for i, expression := range expressions {
+		formatted[i] = p.expr(expression)
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,141 @@
+package format_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/format"
+	"github.com/aselhid/indoscript/internal/lexer"
+)
+
+func formatSource(t *testing.T, source string) string {
+	t.Helper()
+	scanner := lexer.NewScanner(strings.NewReader(source), io.Discard)
+	tokens := scanner.ScanTokensWithComments()
+	stmts, hasError := ast.NewParser(tokens, io.Discard).Parse()
+	if hasError || len(scanner.Errors()) > 0 {
+		t.Fatalf("unexpected syntax error in %q", source)
+	}
+	return format.Source(tokens, stmts)
+}
+
+func TestSource(t *testing.T) {
+	testcases := map[string]struct {
+		source   string
+		expected string
+	}{
+		"empty": {
+			source:   "\n\n",
+			expected: "",
+		},
+		"spacing": {
+			source:   "misal a=1;cetak (a+2)*-a;f(a,[1,2],{\"k\":benar});a[0]=a dan !salah;",
+			expected: "misal a = 1;\ncetak (a + 2) * -a;\nf(a, [1, 2], {\"k\": benar});\na[0] = a dan !salah;\n",
+		},
+		"blocks": {
+			source: "fungsi f(a,b){jika a{balikin;}lain{balikin b;}}selama salah{}{misal x=1;}",
+			expected: "fungsi f(a, b) {\n" +
+				"    jika a {\n        balikin;\n    } lain {\n        balikin b;\n    }\n" +
+				"}\n" +
+				"selama salah {}\n" +
+				"{\n    misal x = 1;\n}\n",
+		},
+		"rest parameter": {
+			source:   "fungsi f(a,... b){}",
+			expected: "fungsi f(a, ...b) {}\n",
+		},
+		"default and named arguments": {
+			source:   "fungsi f(a,b=a*2,c=[1]){}f(1,c:{},b:2);",
+			expected: "fungsi f(a, b = a * 2, c = [1]) {}\nf(1, c: {}, b: 2);\n",
+		},
+		"anonymous functions": {
+			source: "misal f=fungsi(x){balikin x*2;};peta(xs,fungsi (x)=>x+1);\n" +
+				"jika benar {\nulangi(fungsi() { // komentar\ncetak 1;\n}, 2);\n}",
+			expected: "misal f = fungsi (x) {\n    balikin x * 2;\n};\npeta(xs, fungsi (x) => x + 1);\n" +
+				"jika benar {\n    ulangi(fungsi () { // komentar\n        cetak 1;\n    }, 2);\n}\n",
+		},
+		"untuk": {
+			source: "untuk misal i=0;i<3;i=i+1{cetak i;}untuk ;;{}untuk x dalam rentang(3){xs[x]=x;}",
+			expected: "untuk misal i = 0; i < 3; i = i + 1 {\n    cetak i;\n}\nuntuk ;; {}\n" +
+				"untuk x dalam rentang(3) {\n    xs[x] = x;\n}\n",
+		},
+		"berhenti and lanjut": {
+			source:   "luar:selama benar{untuk x dalam xs{jika x{lanjut luar;}berhenti;}}",
+			expected: "luar: selama benar {\n    untuk x dalam xs {\n        jika x {\n            lanjut luar;\n        }\n        berhenti;\n    }\n}\n",
+		},
+		"lain jika": {
+			source:   "jika a{cetak 1;}lain jika b{cetak 2;}\nlain jika c{}lain{cetak 3;}",
+			expected: "jika a {\n    cetak 1;\n} lain jika b {\n    cetak 2;\n} lain jika c {} lain {\n    cetak 3;\n}\n",
+		},
+		"kelas": {
+			source: "kelas A{inisialisasi(x){ini.x=x;}\n\n// dapat\ndapat(){balikin ini.x;}}kelas B{}a.b.c=A(1).dapat();",
+			expected: "kelas A {\n    inisialisasi(x) {\n        ini.x = x;\n    }\n\n    // dapat\n    dapat() {\n        balikin ini.x;\n    }\n}\n" +
+				"kelas B {}\na.b.c = A(1).dapat();\n",
+		},
+		"induk": {
+			source:   "kelas B<A{f(){balikin induk.f()+1;}}",
+			expected: "kelas B < A {\n    f() {\n        balikin induk.f() + 1;\n    }\n}\n",
+		},
+		"coba": {
+			source:   "coba{lempar galat(\"x\");}tangkap(e){cetak e.pesan;}akhirnya{}coba{}akhirnya{cetak 1;}",
+			expected: "coba {\n    lempar galat(\"x\");\n} tangkap (e) {\n    cetak e.pesan;\n} akhirnya {}\ncoba {} akhirnya {\n    cetak 1;\n}\n",
+		},
+		"strings": {
+			source:   "jika benar{cetak \"a\\n\\u{e9}\";cetak `satu\n  dua`;}",
+			expected: "jika benar {\n    cetak \"a\\n\\u{e9}\";\n    cetak `satu\n  dua`;\n}\n",
+		},
+		"interpolation": {
+			source:   "cetak \"a ${ 1+2 } b ${{\"k\":[x]}}\";",
+			expected: "cetak \"a ${1 + 2} b ${{\"k\": [x]}}\";\n",
+		},
+		"comments inside expressions": {
+			source:   "misal a = [1, // di dalam daftar\n  3];\ncetak f(a, // satu\n  b // dua\n); // akhir\ncetak a;",
+			expected: "misal a = [1, 3]; // di dalam daftar\ncetak f(a, b); // satu // dua // akhir\ncetak a;\n",
+		},
+		"comments": {
+			source: "// kepala\nmisal a = 1;   // di belakang\n" +
+				"fungsi f() { // setelah kurawal\n  // di dalam\n  balikin a;\n  // sebelum penutup\n}\n" +
+				"jika a {\n// satu-satunya\n}\n// akhir",
+			expected: "// kepala\nmisal a = 1; // di belakang\n" +
+				"fungsi f() { // setelah kurawal\n    // di dalam\n    balikin a;\n    // sebelum penutup\n}\n" +
+				"jika a {\n    // satu-satunya\n}\n// akhir\n",
+		},
+		"blank lines": {
+			source:   "\n\nmisal a = 1;\n\n\n\nmisal b = 2;\n{\n\n  cetak a;\n\n}\n\n// akhir\n\n",
+			expected: "misal a = 1;\n\nmisal b = 2;\n{\n    cetak a;\n}\n\n// akhir\n",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			actual := formatSource(t, tc.source)
+			if actual != tc.expected {
+				t.Fatalf("expected\n%s\nwhile actual is\n%s", tc.expected, actual)
+			}
+			if again := formatSource(t, actual); again != actual {
+				t.Fatalf("formatting is not idempotent, second pass gives\n%s", again)
+			}
+		})
+	}
+}
+
+func TestSourceIsIdempotentOnExamples(t *testing.T) {
+	filenames, err := filepath.Glob("../../example/*.indos")
+	if err != nil || len(filenames) == 0 {
+		t.Fatalf("no examples found: %v", err)
+	}
+	for _, filename := range filenames {
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted := formatSource(t, string(source))
+		if again := formatSource(t, formatted); again != formatted {
+			t.Errorf("%s: formatting is not idempotent", filename)
+		}
+	}
+}
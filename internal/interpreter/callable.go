@@ -1,43 +1,297 @@
 package interpreter
 
 import (
+	"fmt"
+
 	"github.com/aselhid/indoscript/internal/ast"
 	"github.com/aselhid/indoscript/internal/environment"
 )
 
+// Callable is implemented by every value that can be called from a script.
+// Arity reports how many arguments the callable takes, variadic callables take
+// at least that many. An error returned by Call is reported as a runtime error
+// at the call site.
 type Callable interface {
-	Call(*Interpreter, []any) any
+	Arity() (arity int, variadic bool)
+	Call(*Interpreter, []any) (any, error)
+}
+
+// CheckArity returns the error for calling the callable named name, which
+// takes arity arguments or at least that many when variadic, with argCount
+// arguments. It returns nil when the call is fine.
+func CheckArity(name string, arity int, variadic bool, argCount int) error {
+	if variadic && argCount < arity {
+		return fmt.Errorf("fungsi %s mengharapkan paling sedikit %d argumen, dapat %d", name, arity, argCount)
+	}
+	if !variadic && argCount != arity {
+		return fmt.Errorf("fungsi %s mengharapkan %d argumen, dapat %d", name, arity, argCount)
+	}
+	return nil
+}
+
+// Missing stands in for a parameter no argument was given for, the default
+// value of the parameter is used instead. Scripts never see it.
+var Missing any = missing{}
+
+type missing struct{}
+
+// Signature describes the parameters of a fungsi declared in a script. Both
+// backends use it to match the arguments of a call to the parameters.
+type Signature struct {
+	Name       string
+	Parameters []string
+	// Required is the number of parameters without a default value, they
+	// come before the ones with a default value
+	Required int
+	Variadic bool
+}
+
+// AnonymousName is the name of a fungsi declared without one.
+const AnonymousName = "anonim"
+
+func NewSignature(declaration ast.FuncStmt) Signature {
+	signature := Signature{Name: declaration.Name.Lexeme, Variadic: declaration.Variadic}
+	if signature.Name == "" {
+		signature.Name = AnonymousName
+	}
+	for i, parameter := range declaration.Parameters {
+		signature.Parameters = append(signature.Parameters, parameter.Lexeme)
+		if declaration.Defaults[i] == nil && !(declaration.Variadic && i == len(declaration.Parameters)-1) {
+			signature.Required++
+		}
+	}
+	return signature
+}
+
+// fixed is the number of parameters not counting the rest parameter.
+func (s Signature) fixed() int {
+	if s.Variadic {
+		return len(s.Parameters) - 1
+	}
+	return len(s.Parameters)
+}
+
+// Bind orders the arguments of a call by parameter. names holds the name of
+// every argument given by name and "" for the positional ones before them, it
+// is nil when no argument is named. Parameters left without an argument get
+// Missing and the rest parameter gets a list of the extra arguments.
+func (s Signature) Bind(arguments []any, names []string) ([]any, error) {
+	fixed := s.fixed()
+	if names == nil && !s.Variadic && len(arguments) == fixed {
+		return arguments, nil
+	}
+
+	positional := len(arguments)
+	for i, name := range names {
+		if name != "" {
+			positional = i
+			break
+		}
+	}
+	if positional > fixed && !s.Variadic {
+		return nil, s.arityError(positional)
+	}
+
+	bound := make([]any, len(s.Parameters))
+	for i := 0; i < fixed; i++ {
+		bound[i] = Missing
+	}
+	copy(bound, arguments[:min(positional, fixed)])
+	if s.Variadic {
+		rest := []any{}
+		if positional > fixed {
+			rest = append(rest, arguments[fixed:positional]...)
+		}
+		bound[fixed] = NewList(rest)
+	}
+
+	for i := positional; i < len(arguments); i++ {
+		index := -1
+		for k, parameter := range s.Parameters[:fixed] {
+			if parameter == names[i] {
+				index = k
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("fungsi %s tidak punya parameter bernama %s", s.Name, names[i])
+		}
+		if bound[index] != Missing {
+			return nil, fmt.Errorf("fungsi %s mendapat argumen %s lebih dari sekali", s.Name, names[i])
+		}
+		bound[index] = arguments[i]
+	}
+
+	for i := 0; i < s.Required; i++ {
+		if bound[i] != Missing {
+			continue
+		}
+		if positional == len(arguments) {
+			return nil, s.arityError(positional)
+		}
+		return nil, fmt.Errorf("fungsi %s tidak mendapat argumen untuk parameter %s", s.Name, s.Parameters[i])
+	}
+	return bound, nil
+}
+
+func (s Signature) arityError(argCount int) error {
+	fixed := s.fixed()
+	switch {
+	case s.Variadic:
+		return CheckArity(s.Name, s.Required, true, argCount)
+	case s.Required == fixed:
+		return CheckArity(s.Name, fixed, false, argCount)
+	case argCount < s.Required:
+		return fmt.Errorf("fungsi %s mengharapkan paling sedikit %d argumen, dapat %d", s.Name, s.Required, argCount)
+	}
+	return fmt.Errorf("fungsi %s mengharapkan paling banyak %d argumen, dapat %d", s.Name, fixed, argCount)
 }
 
 type FunctionCallable struct {
 	Declaration ast.FuncStmt
+	Closure     *environment.Environment
+	Signature   Signature
+	// initializer is set for the inisialisasi method of a kelas, which
+	// always returns the objek it set up
+	initializer bool
+}
+
+// Arity reports the parameters without a default value, calls may give
+// arguments for the others too.
+func (f *FunctionCallable) Arity() (int, bool) {
+	return f.Signature.Required, f.Signature.Variadic
 }
 
-type ReturnValue struct {
-	Value any
+func (f *FunctionCallable) Call(interpreter *Interpreter, arguments []any) (any, error) {
+	bound, err := f.Signature.Bind(arguments, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.call(interpreter, bound)
 }
 
-func (f *FunctionCallable) Call(interpreter *Interpreter, arguments []any) (returnValue any) {
-	defer func() {
-		if err := recover(); err != nil {
-			if v, ok := err.(ReturnValue); ok {
-				returnValue = v.Value
-				return
+// call runs the body with arguments already bound to the parameters.
+func (f *FunctionCallable) call(interpreter *Interpreter, arguments []any) (any, error) {
+	if err := interpreter.checkInterrupted(); err != nil {
+		return nil, err
+	}
+	env := environment.NewEnvironment(f.Closure)
+	for i, parameter := range f.Declaration.Parameters {
+		value := arguments[i]
+		if value == Missing {
+			var err error
+			if value, err = interpreter.evaluateIn(f.Declaration.Defaults[i], env); err != nil {
+				return nil, err
 			}
-			panic(err)
 		}
-	}()
+		env.Define(parameter, value)
+	}
 
-	env := environment.NewEnvironment(interpreter.globalEnv)
-	for i, declaration := range f.Declaration.Parameters {
-		env.Define(declaration, arguments[i])
+	completion := interpreter.executeBlock(f.Declaration.Body, env)
+	if completion.Kind == ast.CompletionError {
+		return nil, completion.Err
 	}
-	interpreter.executeBlock(f.Declaration.Body, env)
-	return nil
+	if f.initializer {
+		return f.Closure.GetAt(0, thisToken)
+	}
+	if completion.Kind == ast.CompletionReturn {
+		return completion.Value, nil
+	}
+	return nil, nil
+}
+
+// thisToken names the variable methods find their objek in, superToken the
+// one methods of a kelas that inherits find the induk kelas in.
+var (
+	thisToken  = ast.Token{TokenType: ast.TokenThis, Lexeme: "ini"}
+	superToken = ast.Token{TokenType: ast.TokenSuper, Lexeme: "induk"}
+)
+
+// Bind returns the method f with ini bound to instance.
+func (f *FunctionCallable) Bind(instance *Instance) *FunctionCallable {
+	env := environment.NewEnvironment(f.Closure)
+	env.Define(thisToken, instance)
+	bound := *f
+	bound.Closure = env
+	return &bound
 }
 
-func NewFunctionCallable(declaration ast.FuncStmt) FunctionCallable {
-	return FunctionCallable{
+func (f *FunctionCallable) String() string {
+	return "<fungsi " + f.Signature.Name + ">"
+}
+
+func NewFunctionCallable(declaration ast.FuncStmt, closure *environment.Environment) *FunctionCallable {
+	return &FunctionCallable{
 		Declaration: declaration,
+		Closure:     closure,
+		Signature:   NewSignature(declaration),
+	}
+}
+
+// ClassCallable is a kelas declared in a script. Calling it creates an
+// Instance and runs the inisialisasi method on it, if the kelas has one.
+type ClassCallable struct {
+	Name       string
+	Superclass *ClassCallable
+	Methods    map[string]*FunctionCallable
+}
+
+func (c *ClassCallable) ClassName() string {
+	return c.Name
+}
+
+// FindMethod returns the method called name, not bound to any objek yet.
+// Methods the kelas doesn't declare itself are looked up in its induk kelas.
+func (c *ClassCallable) FindMethod(name string) (*FunctionCallable, bool) {
+	if method, ok := c.Methods[name]; ok {
+		return method, true
+	}
+	if c.Superclass != nil {
+		return c.Superclass.FindMethod(name)
+	}
+	return nil, false
+}
+
+// Arity is the arity of the initializer, a kelas without one takes no
+// arguments.
+func (c *ClassCallable) Arity() (int, bool) {
+	if initializer, ok := c.FindMethod(ast.InitializerName); ok {
+		return initializer.Arity()
+	}
+	return 0, false
+}
+
+func (c *ClassCallable) Call(interpreter *Interpreter, arguments []any) (any, error) {
+	instance := NewInstance(c)
+	if initializer, ok := c.FindMethod(ast.InitializerName); ok {
+		if _, err := initializer.Bind(instance).Call(interpreter, arguments); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+func (c *ClassCallable) String() string {
+	return "<kelas " + c.Name + ">"
+}
+
+// NewClassCallable creates the kelas declared by declaration, its methods
+// close over closure. superclass is nil for a kelas without an induk kelas.
+func NewClassCallable(declaration ast.ClassStmt, superclass *ClassCallable, closure *environment.Environment) *ClassCallable {
+	class := &ClassCallable{
+		Name:       declaration.Name.Lexeme,
+		Superclass: superclass,
+		Methods:    make(map[string]*FunctionCallable),
+	}
+	for _, method := range declaration.Methods {
+		function := NewFunctionCallable(method, closure)
+		if method.Name.Lexeme == ast.InitializerName {
+			// errors about the arguments of the initializer name the kelas
+			// the script called
+			function.Signature.Name = class.Name
+			function.initializer = true
+		}
+		class.Methods[method.Name.Lexeme] = function
 	}
+	return class
 }
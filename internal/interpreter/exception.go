@@ -0,0 +1,107 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/errors"
+)
+
+// Exception is a galat, the value a tangkap clause binds. Runtime errors and
+// values thrown with lempar are caught as one, a thrown galat is caught as
+// itself.
+//
+// Native functions raise a galat by returning an *Exception as their error,
+// Throw makes one out of any value.
+type Exception struct {
+	Message string
+	// Value is the value given to lempar when it wasn't a galat, nil
+	// otherwise
+	Value any
+	// Line and Trace are where the galat was first raised, they stay unset
+	// until then
+	Line  int
+	Trace []errors.StackFrame
+}
+
+// NewException creates a galat with message that hasn't been raised yet.
+func NewException(message string) *Exception {
+	return &Exception{Message: message}
+}
+
+// Throw returns the galat raised by 'lempar value', a galat is thrown as it
+// is and any other value is described by the galat carrying it.
+func Throw(value any) *Exception {
+	if exception, ok := value.(*Exception); ok {
+		return exception
+	}
+	return &Exception{Message: Stringify(value), Value: value}
+}
+
+// Catch returns the galat a tangkap clause binds for err, err should already
+// carry its trace.
+func Catch(err errors.RuntimeError) *Exception {
+	exception, ok := err.Value().(*Exception)
+	if !ok {
+		exception = &Exception{Message: err.Message()}
+	}
+	if exception.Trace == nil {
+		exception.Line = err.Token().LineNumber
+		exception.Trace = err.Trace()
+	}
+	return exception
+}
+
+// ToRuntimeError turns err, returned while running the code at token, into
+// the runtime error that propagates through the script. A galat is raised as
+// thrown, runtime errors are kept and any other error becomes one at token.
+//
+// A galat that hasn't been raised yet is raised as a copy, the caller may
+// return the same *Exception every time and its Line and Trace must not stick
+// to it. Only a galat that was already raised, and is thrown again by
+// lempar, keeps its identity.
+func ToRuntimeError(token ast.Token, err error) error {
+	switch e := err.(type) {
+	case errors.RuntimeError:
+		return e
+	case *Exception:
+		if e.Trace == nil {
+			raised := *e
+			e = &raised
+		}
+		return errors.NewThrownError(token, e.Message, e)
+	}
+	return errors.NewRuntimeError(token, err.Error())
+}
+
+func (e *Exception) Error() string {
+	return e.Message
+}
+
+func (e *Exception) String() string {
+	return "<galat " + e.Message + ">"
+}
+
+// property reads a property of the galat, jejak lists the calls that led to
+// it like a stack trace does, innermost first.
+func (e *Exception) property(name string) (any, bool) {
+	switch name {
+	case "pesan":
+		return e.Message, true
+	case "nilai":
+		return e.Value, true
+	case "baris":
+		return float64(e.Line), true
+	case "jejak":
+		trace := make([]any, len(e.Trace))
+		for i, frame := range e.Trace {
+			function := frame.Function
+			if function == "" {
+				function = "<skrip>"
+			}
+			trace[i] = fmt.Sprintf("%s baris %d", function, frame.Line)
+		}
+		return NewList(trace), true
+	}
+	return nil, false
+}
@@ -0,0 +1,107 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Class is what an Instance knows about the kelas it was created from. The
+// kelas values of the two backends differ in how they hold their methods.
+type Class interface {
+	ClassName() string
+}
+
+// Instance is an objek created by calling a kelas. Fields are added by
+// assigning to them and keep the order they were first assigned in, which is
+// the order they are printed in. Like lists, instances are shared by
+// reference.
+type Instance struct {
+	Class  Class
+	names  []string
+	fields map[string]any
+}
+
+func NewInstance(class Class) *Instance {
+	return &Instance{Class: class, fields: make(map[string]any)}
+}
+
+// Get reads the field name, methods are looked up by the backend when the
+// objek has no such field.
+func (o *Instance) Get(name string) (any, bool) {
+	value, ok := o.fields[name]
+	return value, ok
+}
+
+// Fields returns the names of the fields in the order they were first
+// assigned.
+func (o *Instance) Fields() []string {
+	names := make([]string, len(o.names))
+	copy(names, o.names)
+	return names
+}
+
+func (o *Instance) Set(name string, value any) {
+	if _, ok := o.fields[name]; !ok {
+		o.names = append(o.names, name)
+	}
+	o.fields[name] = value
+}
+
+func (o *Instance) String() string {
+	return o.format(nil)
+}
+
+func (o *Instance) format(printing map[any]bool) string {
+	if printing[o] {
+		return o.Class.ClassName() + "{...}"
+	}
+	printing = enter(printing, o)
+	defer delete(printing, o)
+
+	var builder strings.Builder
+	builder.WriteString(o.Class.ClassName())
+	builder.WriteByte('{')
+	for i, name := range o.names {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(name)
+		builder.WriteString(": ")
+		builder.WriteString(inspect(o.fields[name], printing))
+	}
+	builder.WriteByte('}')
+	return builder.String()
+}
+
+// GetProperty reads object.name for both backends. A field wins over a
+// method, method looks the method up in the kelas of the objek and binds it.
+// A galat has the read-only properties pesan, nilai, baris and jejak.
+func GetProperty(object any, name string, method func(instance *Instance, name string) (any, bool)) (any, error) {
+	if exception, ok := object.(*Exception); ok {
+		if value, ok := exception.property(name); ok {
+			return value, nil
+		}
+		return nil, fmt.Errorf("Undefined property %s", name)
+	}
+	instance, ok := object.(*Instance)
+	if !ok {
+		return nil, fmt.Errorf("can't read property %s of %s", name, TypeName(object))
+	}
+	if value, ok := instance.Get(name); ok {
+		return value, nil
+	}
+	if bound, ok := method(instance, name); ok {
+		return bound, nil
+	}
+	return nil, fmt.Errorf("Undefined property %s", name)
+}
+
+// SetProperty assigns object.name = value, only an objek has properties.
+func SetProperty(object any, name string, value any) error {
+	instance, ok := object.(*Instance)
+	if !ok {
+		return fmt.Errorf("can't set property %s on %s", name, TypeName(object))
+	}
+	instance.Set(name, value)
+	return nil
+}
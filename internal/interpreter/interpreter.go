@@ -1,273 +1,822 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"strconv"
+	"strings"
 
 	"github.com/aselhid/indoscript/internal/ast"
 	"github.com/aselhid/indoscript/internal/environment"
 	"github.com/aselhid/indoscript/internal/errors"
-	"github.com/google/go-cmp/cmp"
 )
 
+// maxFrames bounds the depth of fungsi calls so runaway recursion is a
+// runtime error instead of exhausting the Go stack, it matches the VM.
+const maxFrames = 10000
+
 type Interpreter struct {
 	stdErr    io.Writer
 	stdOut    io.Writer
 	globalEnv *environment.Environment
+	env       *environment.Environment
+	ctx       context.Context
+	frames    []Frame
+	hook      Hook
 }
 
-func (i *Interpreter) Interpret(stmts []ast.Stmt) (hasRuntimeError bool) {
-	defer func() {
-		if err := recover(); err != nil {
-			if e, ok := err.(errors.RuntimeError); ok {
-				i.stdErr.Write([]byte(e.Error() + "\n"))
-			} else {
-				fmt.Printf("Error: %s\n", err)
-			}
-			hasRuntimeError = true
-		}
-	}()
+// Frame is a call in progress. The first frame is the script itself and has
+// no name.
+type Frame struct {
+	Name string
+	// CallSite is the '(' of the call that made the frame, it is the zero
+	// Token for the script itself.
+	CallSite ast.Token
+	// Stmt is the statement being executed and Env the environment it runs
+	// in, they are updated as execution moves through the frame.
+	Stmt ast.Stmt
+	Env  *environment.Environment
+}
+
+// Line returns the line of the statement being executed.
+func (f Frame) Line() int {
+	return ast.StmtStart(f.Stmt).LineNumber
+}
+
+// Hook is called before every statement is executed with the active frames,
+// the innermost frame last. The script waits for the hook to return, which is
+// how a debugger pauses it. frames must not be kept after returning.
+type Hook func(stmt ast.Stmt, frames []Frame)
 
+// normal is the completion of a statement that lets execution carry on with
+// the next one.
+var normal = ast.Completion{}
+
+func failed(err error) ast.Completion {
+	return ast.Completion{Kind: ast.CompletionError, Err: err}
+}
+
+func (i *Interpreter) Interpret(stmts []ast.Stmt) error {
+	return i.InterpretContext(context.Background(), stmts)
+}
+
+// InterpretContext runs stmts until they finish, a runtime error happens or ctx
+// is done. The returned error is either an errors.RuntimeError or ctx.Err().
+func (i *Interpreter) InterpretContext(ctx context.Context, stmts []ast.Stmt) error {
+	i.begin(ctx)
 	for _, stmt := range stmts {
-		i.execute(stmt)
+		if completion := i.execute(stmt); completion.Kind == ast.CompletionError {
+			return i.traced(completion.Err)
+		}
 	}
-	return false
+	return nil
 }
 
-func (i *Interpreter) VisitVarStmt(stmt ast.VarStmt) {
-	value := i.evaluate(stmt.Expression)
-	i.globalEnv.Define(stmt.Identifier, value)
+// EvaluateContext evaluates a single expression in the global environment,
+// it fails the same way InterpretContext does.
+func (i *Interpreter) EvaluateContext(ctx context.Context, expr ast.Expr) (any, error) {
+	i.begin(ctx)
+	value, err := i.evaluate(expr)
+	if err != nil {
+		return nil, i.traced(err)
+	}
+	return value, nil
 }
 
-func (i *Interpreter) VisitPrintStmt(stmt ast.PrintStmt) {
-	expr := i.evaluate(stmt.Expression)
-	i.stdOut.Write([]byte(i.stringify(expr) + "\n"))
+// begin prepares to run from the top level of the script.
+func (i *Interpreter) begin(ctx context.Context) {
+	i.ctx = ctx
+	i.env = i.globalEnv
+	i.frames = append(i.frames[:0], Frame{Env: i.globalEnv})
 }
 
-func (i *Interpreter) VisitExprStmt(stmt ast.ExprStmt) {
-	i.evaluate(stmt.Expression)
+// SetHook installs hook to be called before every statement, nil removes it.
+func (i *Interpreter) SetHook(hook Hook) {
+	i.hook = hook
 }
 
-func (i *Interpreter) VisitBlockStmt(stmt ast.BlockStmt) {
-	env := environment.NewEnvironment(i.globalEnv)
-	i.executeBlock(stmt.Statements, env)
+// Define binds a global variable, it is how hosts hand values to a script
+// before interpreting it.
+func (i *Interpreter) Define(name string, value any) {
+	i.globalEnv.Define(ast.Token{TokenType: ast.TokenIdentifier, Lexeme: name}, value)
 }
 
-func (i *Interpreter) VisitIfStmt(stmt ast.IfStmt) {
-	value := i.evaluate(stmt.Condition)
-	if i.isTruthy(value) {
-		i.VisitBlockStmt(stmt.ThenStmt)
+func (i *Interpreter) VisitVarStmt(stmt ast.VarStmt) ast.Completion {
+	value, err := i.evaluate(stmt.Expression)
+	if err != nil {
+		return failed(err)
+	}
+	i.env.Define(stmt.Identifier, value)
+	return normal
+}
+
+func (i *Interpreter) VisitAssignStmt(stmt ast.AssignStmt) ast.Completion {
+	value, err := i.evaluate(stmt.Expression)
+	if err != nil {
+		return failed(err)
+	}
+	if stmt.Binding.Depth < 0 {
+		if err := i.globalEnv.Assign(stmt.Identifier, value); err != nil {
+			return failed(err)
+		}
 	} else {
-		i.VisitBlockStmt(stmt.ElseStmt)
+		i.env.AssignAt(stmt.Binding.Depth, stmt.Identifier, value)
 	}
+	return normal
 }
 
-func (i *Interpreter) VisitWhileStmt(stmt ast.WhileStmt) {
-	env := environment.NewEnvironment(i.globalEnv)
-	i.executeLoop(stmt.Condition, stmt.Stmt.Statements, env)
+func (i *Interpreter) VisitPrintStmt(stmt ast.PrintStmt) ast.Completion {
+	value, err := i.evaluate(stmt.Expression)
+	if err != nil {
+		return failed(err)
+	}
+	i.stdOut.Write([]byte(i.stringify(value) + "\n"))
+	return normal
 }
 
-func (i *Interpreter) VisitFuncStmt(stmt ast.FuncStmt) {
-	function := NewFunctionCallable(stmt)
-	i.globalEnv.Define(stmt.Name, function)
+func (i *Interpreter) VisitExprStmt(stmt ast.ExprStmt) ast.Completion {
+	if _, err := i.evaluate(stmt.Expression); err != nil {
+		return failed(err)
+	}
+	return normal
 }
 
-func (i *Interpreter) VisitReturnStmt(stmt ast.ReturnStmt) {
+func (i *Interpreter) VisitIndexAssignStmt(stmt ast.IndexAssignStmt) ast.Completion {
+	object, err := i.evaluate(stmt.Object)
+	if err != nil {
+		return failed(err)
+	}
+	index, err := i.evaluate(stmt.Index)
+	if err != nil {
+		return failed(err)
+	}
+	value, err := i.evaluate(stmt.Expression)
+	if err != nil {
+		return failed(err)
+	}
+
+	if err := SetIndex(object, index, value); err != nil {
+		return failed(i.error(stmt.Bracket, err.Error()))
+	}
+	return normal
+}
+
+func (i *Interpreter) VisitPropertyAssignStmt(stmt ast.PropertyAssignStmt) ast.Completion {
+	object, err := i.evaluate(stmt.Object)
+	if err != nil {
+		return failed(err)
+	}
+	value, err := i.evaluate(stmt.Expression)
+	if err != nil {
+		return failed(err)
+	}
+
+	if err := SetProperty(object, stmt.Name.Lexeme, value); err != nil {
+		return failed(i.error(stmt.Name, err.Error()))
+	}
+	return normal
+}
+
+func (i *Interpreter) VisitBlockStmt(stmt ast.BlockStmt) ast.Completion {
+	env := environment.NewEnvironment(i.env)
+	return i.executeBlock(stmt.Statements, env)
+}
+
+func (i *Interpreter) VisitIfStmt(stmt ast.IfStmt) ast.Completion {
+	value, err := i.evaluate(stmt.Condition)
+	if err != nil {
+		return failed(err)
+	}
+	if i.isTruthy(value) {
+		return i.VisitBlockStmt(stmt.ThenStmt)
+	}
+	switch elseStmt := stmt.ElseStmt.(type) {
+	case ast.BlockStmt:
+		return i.VisitBlockStmt(elseStmt)
+	case ast.IfStmt:
+		// the condition of a lain jika branch is a step of its own for the
+		// debugger, like a statement on its line
+		return i.execute(elseStmt)
+	}
+	return normal
+}
+
+func (i *Interpreter) VisitWhileStmt(stmt ast.WhileStmt) ast.Completion {
+	return i.executeLoop(stmt.Label, stmt.Condition, stmt.Stmt.Statements)
+}
+
+// VisitForStmt runs the initializer in an environment of its own, which is
+// copied before every increment so each iteration keeps its own variables.
+func (i *Interpreter) VisitForStmt(stmt ast.ForStmt) ast.Completion {
+	previousEnv := i.env
+	defer func() { i.env = previousEnv }()
+
+	i.env = environment.NewEnvironment(previousEnv)
+	if stmt.Initializer != nil {
+		if completion := stmt.Initializer.Accept(i); completion.Kind != ast.CompletionNormal {
+			return completion
+		}
+	}
+	for {
+		if stmt.Condition != nil {
+			value, err := i.evaluate(stmt.Condition)
+			if err != nil {
+				return failed(err)
+			}
+			if !i.isTruthy(value) {
+				return normal
+			}
+		}
+		if err := i.checkInterrupted(); err != nil {
+			return failed(err)
+		}
+
+		completion := i.executeBlock(stmt.Body.Statements, environment.NewEnvironment(i.env))
+		if result, done := loopControl(stmt.Label, completion); done {
+			return result
+		}
+
+		i.env = i.env.Copy()
+		if stmt.Increment != nil {
+			if completion := stmt.Increment.Accept(i); completion.Kind != ast.CompletionNormal {
+				return completion
+			}
+		}
+	}
+}
+
+func (i *Interpreter) VisitForInStmt(stmt ast.ForInStmt) ast.Completion {
+	iterable, err := i.evaluate(stmt.Iterable)
+	if err != nil {
+		return failed(err)
+	}
+	iterator, err := NewIterator(iterable)
+	if err != nil {
+		return failed(i.error(stmt.Keyword, err.Error()))
+	}
+	for {
+		element, ok := iterator.Next()
+		if !ok {
+			return normal
+		}
+		if err := i.checkInterrupted(); err != nil {
+			return failed(err)
+		}
+
+		env := environment.NewEnvironment(i.env)
+		env.Define(stmt.Variable, element)
+		completion := i.executeBlock(stmt.Body.Statements, environment.NewEnvironment(env))
+		if result, done := loopControl(stmt.Label, completion); done {
+			return result
+		}
+	}
+}
+
+func (i *Interpreter) VisitFuncStmt(stmt ast.FuncStmt) ast.Completion {
+	function := NewFunctionCallable(stmt, i.env)
+	i.env.Define(stmt.Name, function)
+	return normal
+}
+
+func (i *Interpreter) VisitClassStmt(stmt ast.ClassStmt) ast.Completion {
+	var superclass *ClassCallable
+	closure := i.env
+	if stmt.Superclass != nil {
+		value, err := i.evaluate(*stmt.Superclass)
+		if err != nil {
+			return failed(err)
+		}
+		class, ok := value.(*ClassCallable)
+		if !ok {
+			return failed(i.error(stmt.Superclass.Identifier, "induk kelas must be a kelas"))
+		}
+		superclass = class
+		closure = environment.NewEnvironment(i.env)
+		closure.Define(superToken, superclass)
+	}
+
+	i.env.Define(stmt.Name, NewClassCallable(stmt, superclass, closure))
+	return normal
+}
+
+func (i *Interpreter) VisitReturnStmt(stmt ast.ReturnStmt) ast.Completion {
 	var value any
 	if stmt.Value != nil {
-		value = i.evaluate(stmt.Value)
+		var err error
+		if value, err = i.evaluate(stmt.Value); err != nil {
+			return failed(err)
+		}
+	}
+	return ast.Completion{Kind: ast.CompletionReturn, Value: value}
+}
+
+func (i *Interpreter) VisitBreakStmt(stmt ast.BreakStmt) ast.Completion {
+	return ast.Completion{Kind: ast.CompletionBreak, Label: stmt.Label.Lexeme}
+}
+
+func (i *Interpreter) VisitContinueStmt(stmt ast.ContinueStmt) ast.Completion {
+	return ast.Completion{Kind: ast.CompletionContinue, Label: stmt.Label.Lexeme}
+}
+
+func (i *Interpreter) VisitThrowStmt(stmt ast.ThrowStmt) ast.Completion {
+	value, err := i.evaluate(stmt.Value)
+	if err != nil {
+		return failed(err)
+	}
+	return failed(ToRuntimeError(stmt.Keyword, Throw(value)))
+}
+
+// VisitTryStmt catches runtime errors of the body, not a cancelled context.
+// An akhirnya block that completes abruptly replaces how the body or the
+// tangkap block completed.
+func (i *Interpreter) VisitTryStmt(stmt ast.TryStmt) ast.Completion {
+	completion := i.execute(stmt.Body)
+	if completion.Kind == ast.CompletionError && stmt.Catch != nil {
+		// the error hasn't left the frame it was raised in, its trace is
+		// still the current one
+		if err, ok := i.traced(completion.Err).(errors.RuntimeError); ok {
+			env := environment.NewEnvironment(i.env)
+			env.Define(stmt.CatchName, Catch(err))
+			completion = i.executeBlock(stmt.Catch.Statements, env)
+		}
+	}
+	if stmt.Finally != nil {
+		if finally := i.execute(*stmt.Finally); finally.Kind != ast.CompletionNormal {
+			return finally
+		}
 	}
-	panic(ReturnValue{Value: value})
+	return completion
 }
 
-func (i *Interpreter) VisitBinaryExpr(expr ast.BinaryExpr) any {
-	left := i.evaluate(expr.Left)
-	right := i.evaluate(expr.Right)
+func (i *Interpreter) VisitBinaryExpr(expr ast.BinaryExpr) (any, error) {
+	left, err := i.evaluate(expr.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := i.evaluate(expr.Right)
+	if err != nil {
+		return nil, err
+	}
 
 	switch expr.Operator.TokenType {
 	case ast.TokenMinus:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) - right.(float64)
+		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+			return nil, err
+		}
+		return left.(float64) - right.(float64), nil
 	case ast.TokenSlash:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) / right.(float64)
+		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+			return nil, err
+		}
+		return left.(float64) / right.(float64), nil
 	case ast.TokenStar:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) * right.(float64)
+		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+			return nil, err
+		}
+		return left.(float64) * right.(float64), nil
 	case ast.TokenPlus:
 		leftAsNumber, leftIsNumber := left.(float64)
 		rightAsNumber, rightIsNumber := right.(float64)
 		if leftIsNumber && rightIsNumber {
-			return leftAsNumber + rightAsNumber
+			return leftAsNumber + rightAsNumber, nil
 		}
 
 		leftAsString, leftIsString := left.(string)
 		rightAsString, rightIsString := right.(string)
 		if leftIsString && rightIsString {
-			return leftAsString + rightAsString
+			return leftAsString + rightAsString, nil
 		}
 
-		i.error(expr.Operator, "operands must be either numbers or strings")
+		return nil, i.error(expr.Operator, "operands must be either numbers or strings")
 	case ast.TokenGreater:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) > right.(float64)
+		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+			return nil, err
+		}
+		return left.(float64) > right.(float64), nil
 	case ast.TokenGreaterEqual:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) >= right.(float64)
+		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+			return nil, err
+		}
+		return left.(float64) >= right.(float64), nil
 	case ast.TokenLess:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) < right.(float64)
+		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+			return nil, err
+		}
+		return left.(float64) < right.(float64), nil
 	case ast.TokenLessEqual:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) <= right.(float64)
+		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+			return nil, err
+		}
+		return left.(float64) <= right.(float64), nil
 	case ast.TokenAnd:
-		return i.isTruthy(left) && i.isTruthy(right)
+		return i.isTruthy(left) && i.isTruthy(right), nil
 	case ast.TokenOr:
-		return i.isTruthy(left) || i.isTruthy(right)
+		return i.isTruthy(left) || i.isTruthy(right), nil
 	case ast.TokenEqualEqual:
-		return cmp.Equal(left, right)
+		return i.isEqual(left, right), nil
 	case ast.TokenBangEqual:
-		return !cmp.Equal(left, right)
+		return !i.isEqual(left, right), nil
 	}
-	return nil
+	return nil, nil
 }
 
-func (i *Interpreter) VisitLogicalExpr(expr ast.LogicalExpr) any {
-	left := i.evaluate(expr.Left)
+func (i *Interpreter) VisitLogicalExpr(expr ast.LogicalExpr) (any, error) {
+	left, err := i.evaluate(expr.Left)
+	if err != nil {
+		return nil, err
+	}
 
 	if expr.Operator.TokenType == ast.TokenOr {
 		if i.isTruthy(left) {
-			return left
+			return left, nil
 		}
 	} else {
 		if !i.isTruthy(left) {
-			return left
+			return left, nil
 		}
 	}
 	return i.evaluate(expr.Right)
 }
 
-func (i *Interpreter) VisitUnaryExpr(expr ast.UnaryExpr) any {
-	right := i.evaluate(expr.Right)
+func (i *Interpreter) VisitUnaryExpr(expr ast.UnaryExpr) (any, error) {
+	right, err := i.evaluate(expr.Right)
+	if err != nil {
+		return nil, err
+	}
 
 	switch expr.Operator.TokenType {
 	case ast.TokenMinus:
-		i.checkNumberOperand(expr.Operator, right)
-		return -right.(float64)
+		if err := i.checkNumberOperand(expr.Operator, right); err != nil {
+			return nil, err
+		}
+		return -right.(float64), nil
 	case ast.TokenBang:
-		return !i.isTruthy(right)
+		return !i.isTruthy(right), nil
 	}
-	return nil
+	return nil, nil
 }
 
-func (i *Interpreter) VisitPrimaryExpr(expr ast.PrimaryExpr) any {
-	return expr.Literal
+func (i *Interpreter) VisitPrimaryExpr(expr ast.PrimaryExpr) (any, error) {
+	return expr.Literal, nil
 }
 
-func (i *Interpreter) VisitGroupExpr(expr ast.GroupExpr) any {
-	return i.evaluate(expr)
+func (i *Interpreter) VisitGroupExpr(expr ast.GroupExpr) (any, error) {
+	return i.evaluate(expr.Expression)
 }
 
-func (i *Interpreter) VisitVarExpr(expr ast.VarExpr) any {
-	return i.globalEnv.Get(expr.Identifier)
+func (i *Interpreter) VisitVarExpr(expr ast.VarExpr) (any, error) {
+	if expr.Binding.Depth < 0 {
+		return i.globalEnv.Get(expr.Identifier)
+	}
+	return i.env.GetAt(expr.Binding.Depth, expr.Identifier)
 }
 
-func (i *Interpreter) VisitCallExpr(expr ast.CallExpr) any {
-	callee := i.evaluate(expr.Callee)
-	var arguments []any
+func (i *Interpreter) VisitCallExpr(expr ast.CallExpr) (any, error) {
+	callee, err := i.evaluate(expr.Callee)
+	if err != nil {
+		return nil, err
+	}
+	arguments := make([]any, 0, len(expr.Arguments))
 	for _, argExpr := range expr.Arguments {
-		arguments = append(arguments, i.evaluate(argExpr))
+		argument, err := i.evaluate(argExpr)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
 	}
-	function, ok := callee.(FunctionCallable)
+	function, ok := callee.(Callable)
 	if !ok {
-		i.error(expr.Parenthesis, "fungsi call is not callable")
+		return nil, i.error(expr.Parenthesis, "fungsi call is not callable")
 	}
-	return function.Call(i, arguments)
+
+	switch callable := function.(type) {
+	case *FunctionCallable:
+		return i.callFunction(callable, arguments, expr)
+	case *ClassCallable:
+		if initializer, ok := callable.FindMethod(ast.InitializerName); ok {
+			return i.callFunction(initializer.Bind(NewInstance(callable)), arguments, expr)
+		}
+	}
+
+	if expr.Names != nil {
+		return nil, i.error(expr.Parenthesis, fmt.Sprintf("fungsi %s tidak menerima argumen bernama", callableName(function)))
+	}
+	arity, variadic := function.Arity()
+	if err := CheckArity(callableName(function), arity, variadic, len(arguments)); err != nil {
+		return nil, i.error(expr.Parenthesis, err.Error())
+	}
+	value, err := function.Call(i, arguments)
+	if err != nil {
+		return nil, ToRuntimeError(expr.Parenthesis, err)
+	}
+	return value, nil
 }
 
-func (i *Interpreter) isTruthy(value any) bool {
-	switch v := value.(type) {
-	case float64:
-		return v != 0.0
-	case string:
-		return v != ""
-	case bool:
-		return v
-	default:
-		return false
+func (i *Interpreter) VisitListExpr(expr ast.ListExpr) (any, error) {
+	elements := make([]any, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		value, err := i.evaluate(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+	}
+	return NewList(elements), nil
+}
+
+func (i *Interpreter) VisitIndexExpr(expr ast.IndexExpr) (any, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := Index(object, index)
+	if err != nil {
+		return nil, i.error(expr.Bracket, err.Error())
+	}
+	return value, nil
+}
+
+func (i *Interpreter) VisitPropertyExpr(expr ast.PropertyExpr) (any, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := GetProperty(object, expr.Name.Lexeme, func(instance *Instance, name string) (any, bool) {
+		method, ok := instance.Class.(*ClassCallable).FindMethod(name)
+		if !ok {
+			return nil, false
+		}
+		return method.Bind(instance), true
+	})
+	if err != nil {
+		return nil, i.error(expr.Name, err.Error())
+	}
+	return value, nil
+}
+
+func (i *Interpreter) VisitThisExpr(expr ast.ThisExpr) (any, error) {
+	return i.env.GetAt(expr.Binding.Depth, expr.Keyword)
+}
+
+func (i *Interpreter) VisitSuperExpr(expr ast.SuperExpr) (any, error) {
+	value, err := i.env.GetAt(expr.Binding.Depth, expr.Keyword)
+	if err != nil {
+		return nil, err
+	}
+	// ini is declared in the scope right inside the one holding induk
+	instance, err := i.env.GetAt(expr.Binding.Depth-1, thisToken)
+	if err != nil {
+		return nil, err
+	}
+
+	method, ok := value.(*ClassCallable).FindMethod(expr.Method.Lexeme)
+	if !ok {
+		return nil, i.error(expr.Method, fmt.Sprintf("Undefined property %s", expr.Method.Lexeme))
+	}
+	return method.Bind(instance.(*Instance)), nil
+}
+
+func (i *Interpreter) VisitMapExpr(expr ast.MapExpr) (any, error) {
+	result := NewMap()
+	for index := range expr.Keys {
+		key, err := i.evaluate(expr.Keys[index])
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckKey(key); err != nil {
+			return nil, i.error(expr.Brace, err.Error())
+		}
+		value, err := i.evaluate(expr.Values[index])
+		if err != nil {
+			return nil, err
+		}
+		result.Set(key, value)
 	}
+	return result, nil
 }
 
-func (i *Interpreter) checkNumberOperand(token ast.Token, operand any) {
+// callFunction calls a fungsi declared in the script, binding the arguments
+// of expr by name where they have one.
+func (i *Interpreter) callFunction(callable *FunctionCallable, arguments []any, expr ast.CallExpr) (any, error) {
+	var names []string
+	for _, name := range expr.Names {
+		names = append(names, name.Lexeme)
+	}
+	arguments, err := callable.Signature.Bind(arguments, names)
+	if err != nil {
+		return nil, i.error(expr.Parenthesis, err.Error())
+	}
+	if len(i.frames) == maxFrames {
+		return nil, i.error(expr.Parenthesis, "stack overflow")
+	}
+	i.pushFrame(callable.Signature.Name, expr.Parenthesis)
+	value, err := callable.call(i, arguments)
+	if err != nil {
+		// the trace is taken while the failing frame is still active
+		err = i.traced(err)
+	}
+	i.popFrame()
+	return value, err
+}
+
+// callableName is the name a callable is declared with.
+func callableName(callable Callable) string {
+	switch c := callable.(type) {
+	case *FunctionCallable:
+		return c.Signature.Name
+	case *NativeFunction:
+		return c.Name
+	case *ClassCallable:
+		return c.Name
+	}
+	return ""
+}
+
+func (i *Interpreter) VisitFuncExpr(expr ast.FuncExpr) (any, error) {
+	return NewFunctionCallable(expr.Declaration, i.env), nil
+}
+
+// VisitTemplateExpr builds the teks by writing every interpolated value
+// between the segments, formatted the way cetak prints it.
+func (i *Interpreter) VisitTemplateExpr(expr ast.TemplateExpr) (any, error) {
+	var result strings.Builder
+	for index, value := range expr.Values {
+		result.WriteString(expr.Segments[index].Literal.(string))
+		evaluated, err := i.evaluate(value)
+		if err != nil {
+			return nil, err
+		}
+		result.WriteString(i.stringify(evaluated))
+	}
+	result.WriteString(expr.Segments[len(expr.Values)].Literal.(string))
+	return result.String(), nil
+}
+
+func (i *Interpreter) isTruthy(value any) bool {
+	return IsTruthy(value)
+}
+
+func (i *Interpreter) isEqual(left, right any) bool {
+	return IsEqual(left, right)
+}
+
+func (i *Interpreter) checkNumberOperand(token ast.Token, operand any) error {
 	if _, ok := operand.(float64); ok {
-		return
+		return nil
 	}
-	i.error(token, "operand must be a number")
+	return i.error(token, "operand must be a number")
 }
 
-func (i *Interpreter) checkNumberOperands(token ast.Token, left, right any) {
+func (i *Interpreter) checkNumberOperands(token ast.Token, left, right any) error {
 	if _, ok := left.(float64); ok {
 		if _, ok := right.(float64); ok {
-			return
+			return nil
 		}
 	}
-	i.error(token, "operands must be numbers")
+	return i.error(token, "operands must be numbers")
 }
 
-func (i *Interpreter) evaluate(expr ast.Expr) any {
+func (i *Interpreter) evaluate(expr ast.Expr) (any, error) {
 	return expr.Accept(i)
 }
 
-func (i *Interpreter) execute(stmt ast.Stmt) {
-	stmt.Accept(i)
+// evaluateIn evaluates expr as if it appeared where env is the innermost
+// environment.
+func (i *Interpreter) evaluateIn(expr ast.Expr, env *environment.Environment) (any, error) {
+	previousEnv := i.env
+	i.env = env
+	value, err := i.evaluate(expr)
+	i.env = previousEnv
+	return value, err
 }
 
-func (i *Interpreter) executeBlock(stmts []ast.Stmt, env *environment.Environment) {
-	previousEnv := i.globalEnv
-	i.globalEnv = env
-	for _, stmt := range stmts {
-		i.execute(stmt)
+func (i *Interpreter) execute(stmt ast.Stmt) ast.Completion {
+	frame := &i.frames[len(i.frames)-1]
+	frame.Stmt, frame.Env = stmt, i.env
+	if i.hook != nil {
+		i.hook(stmt, i.frames)
+		// a debugger stops the script by cancelling the context while it is
+		// paused in the hook
+		if err := i.checkInterrupted(); err != nil {
+			return failed(err)
+		}
+	}
+	return stmt.Accept(i)
+}
+
+func (i *Interpreter) pushFrame(name string, callSite ast.Token) {
+	i.frames = append(i.frames, Frame{Name: name, CallSite: callSite, Env: i.env})
+}
+
+func (i *Interpreter) popFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+// traced gives a runtime error the stack trace as it is now, unless it already
+// has one from a deeper frame.
+func (i *Interpreter) traced(err error) error {
+	if e, ok := err.(errors.RuntimeError); ok && e.Trace() == nil {
+		return e.WithTrace(i.stackTrace(e.Token()))
+	}
+	return err
+}
+
+// stackTrace describes the active frames for an error reported at token, the
+// innermost frame first.
+func (i *Interpreter) stackTrace(token ast.Token) []errors.StackFrame {
+	trace := make([]errors.StackFrame, 0, len(i.frames))
+	line := token.LineNumber
+	for k := len(i.frames) - 1; k >= 0; k-- {
+		trace = append(trace, errors.StackFrame{Function: i.frames[k].Name, Line: line})
+		line = i.frames[k].CallSite.LineNumber
 	}
-	i.globalEnv = previousEnv
+	return trace
 }
 
-func (i *Interpreter) executeLoop(condition ast.Expr, stmts []ast.Stmt, env *environment.Environment) {
-	previousEnv := i.globalEnv
-	i.globalEnv = env
-	for i.isTruthy(i.evaluate(condition)) {
-		for _, stmt := range stmts {
-			i.execute(stmt)
+// executeBlock runs stmts in env until one of them completes abruptly, that
+// completion is handed back so the enclosing statement can act on it.
+func (i *Interpreter) executeBlock(stmts []ast.Stmt, env *environment.Environment) ast.Completion {
+	previousEnv := i.env
+	i.env = env
+	for _, stmt := range stmts {
+		if completion := i.execute(stmt); completion.Kind != ast.CompletionNormal {
+			i.env = previousEnv
+			return completion
 		}
 	}
-	i.globalEnv = previousEnv
+	i.env = previousEnv
+	return normal
 }
 
-func (i *Interpreter) error(token ast.Token, message string) {
-	err := errors.NewRuntimeError(token, message)
-	i.stdErr.Write([]byte(fmt.Sprintf("[line %d] Runtime error: %s\n", token.LineNumber, err.Error())))
-	panic(err)
+// executeLoop runs the body in a fresh environment on every iteration so
+// closures created inside the body capture that iteration's variables.
+func (i *Interpreter) executeLoop(label ast.Token, condition ast.Expr, stmts []ast.Stmt) ast.Completion {
+	for {
+		value, err := i.evaluate(condition)
+		if err != nil {
+			return failed(err)
+		}
+		if !i.isTruthy(value) {
+			return normal
+		}
+		if err := i.checkInterrupted(); err != nil {
+			return failed(err)
+		}
+
+		completion := i.executeBlock(stmts, environment.NewEnvironment(i.env))
+		if result, done := loopControl(label, completion); done {
+			return result
+		}
+	}
 }
 
-func (i *Interpreter) stringify(value any) string {
-	switch v := value.(type) {
-	case float64:
-		return strconv.FormatFloat(v, 'f', -1, 64)
-	case string:
-		return v
-	case bool:
-		if v {
-			return "benar"
+// loopControl decides what the loop labeled label does after its body
+// completed with completion: done is false when the loop goes on with its next
+// iteration, otherwise the loop ends with result. A break or continue aimed
+// at an outer loop ends this loop and is passed on.
+func loopControl(label ast.Token, completion ast.Completion) (result ast.Completion, done bool) {
+	switch completion.Kind {
+	case ast.CompletionNormal:
+		return normal, false
+	case ast.CompletionBreak, ast.CompletionContinue:
+		if completion.Label != "" && completion.Label != label.Lexeme {
+			return completion, true
 		}
-		return "salah"
+		return normal, completion.Kind == ast.CompletionBreak
 	}
-	return "unknown value"
+	return completion, true
+}
+
+// checkInterrupted returns ctx.Err() once the context given to
+// InterpretContext is done.
+func (i *Interpreter) checkInterrupted() error {
+	return i.ctx.Err()
+}
+
+func (i *Interpreter) error(token ast.Token, message string) error {
+	return errors.NewRuntimeError(token, message)
+}
+
+func (i *Interpreter) stringify(value any) string {
+	return Stringify(value)
 }
 
 func NewInterpreter(stdOut, stdErr io.Writer) *Interpreter {
+	globalEnv := environment.NewEnvironment(nil)
+	for _, native := range Builtins() {
+		globalEnv.Define(ast.Token{TokenType: ast.TokenIdentifier, Lexeme: native.Name}, native)
+	}
 	return &Interpreter{
 		stdOut:    stdOut,
 		stdErr:    stdErr,
-		globalEnv: environment.NewEnvironment(nil),
+		globalEnv: globalEnv,
+		env:       globalEnv,
+		ctx:       context.Background(),
 	}
 }
@@ -0,0 +1,869 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/errors"
+	"github.com/aselhid/indoscript/internal/lexer"
+	"github.com/aselhid/indoscript/internal/resolver"
+)
+
+func TestClosureCapturesDefiningScope(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal a = "global";
+{
+    fungsi tampilkan() {
+        cetak a;
+    }
+    tampilkan();
+    misal a = "blok";
+    tampilkan();
+}
+`)
+	compareOutput(t, "global\nglobal\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestClosureCounter(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi buat_penghitung() {
+    misal hitungan = 0;
+    fungsi tambah() {
+        hitungan = hitungan + 1;
+        balikin hitungan;
+    }
+    balikin tambah;
+}
+
+misal pertama = buat_penghitung();
+misal kedua = buat_penghitung();
+cetak pertama();
+cetak pertama();
+cetak kedua();
+cetak pertama();
+`)
+	compareOutput(t, "1\n2\n1\n3\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestNestedFunctionSeesOuterParameters(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi tambah(a) {
+    fungsi dengan(b) {
+        balikin a + b;
+    }
+    balikin dengan;
+}
+
+cetak tambah(1)(2);
+misal a = 100;
+cetak tambah(10)(5);
+`)
+	compareOutput(t, "3\n15\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestAssignmentUpdatesEnclosingScope(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal a = 0;
+selama a < 3 {
+    a = a + 1;
+}
+cetak a;
+`)
+	compareOutput(t, "3\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestResolverErrors(t *testing.T) {
+	testcases := map[string]string{
+		"balikin 1;":                                "can't use balikin outside of a fungsi",
+		"{ misal a = a; }":                          "can't read local variable in its own initializer",
+		"{ misal a = 1; misal a = 2; }":             "already declared in this scope",
+		"fungsi f(a) { misal a = 1; }":              "already declared in this scope",
+		"fungsi f() { { misal b = b; } }":           "can't read local variable in its own initializer",
+		"fungsi f() { balikin 1; } balikin;":        "can't use balikin outside of a fungsi",
+		"cetak ini;":                                "can't use ini outside of a kelas",
+		"fungsi f() { balikin ini; }":               "can't use ini outside of a kelas",
+		"kelas A { inisialisasi() { balikin 1; } }": "can't balikin a value from inisialisasi",
+		"kelas A < A {}":                            "a kelas can't inherit from itself",
+		"cetak induk.f;":                            "can't use induk outside of a kelas",
+		"kelas A { f() { induk.f(); } }":            "can't use induk in a kelas without an induk kelas",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func runScript(t *testing.T, source string) (string, string) {
+	t.Helper()
+	stdOut := new(strings.Builder)
+	stdErr := new(strings.Builder)
+
+	tokens := lexer.NewScanner(strings.NewReader(source), stdErr).ScanTokens()
+	stmts, hasError := ast.NewParser(tokens, stdErr).Parse()
+	if hasError {
+		return stdOut.String(), stdErr.String()
+	}
+	if hasError := resolver.NewResolver(stdErr).Resolve(stmts); hasError {
+		return stdOut.String(), stdErr.String()
+	}
+	if err := NewInterpreter(stdOut, stdErr).Interpret(stmts); err != nil {
+		if e, ok := err.(errors.RuntimeError); ok {
+			fmt.Fprintf(stdErr, "[line %d] ", e.Token().LineNumber)
+		}
+		stdErr.WriteString(err.Error() + "\n")
+	}
+	return stdOut.String(), stdErr.String()
+}
+
+func compareOutput(t *testing.T, expected, actual string) {
+	t.Helper()
+	if expected != actual {
+		t.Fatalf("expected output %q while actual is %q", expected, actual)
+	}
+}
+
+func checkStdErrEmpty(t *testing.T, stdErr string) {
+	t.Helper()
+	if stdErr != "" {
+		t.Fatalf("stdErr is not empty, %s", stdErr)
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+cetak panjang("halo");
+cetak panjang("ñandú");
+cetak waktu_sekarang() > 0;
+cetak panjang;
+`)
+	compareOutput(t, "4\n5\nbenar\n<fungsi bawaan panjang>\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestCallErrors(t *testing.T) {
+	testcases := map[string]string{
+		`panjang(1);`:                 "panjang can't be used on angka",
+		`panjang("a", "b");`:          "fungsi panjang mengharapkan 1 argumen, dapat 2",
+		`fungsi f(a) {} f();`:         "fungsi f mengharapkan 1 argumen, dapat 0",
+		`fungsi f(a) {} f(1, 2);`:     "fungsi f mengharapkan 1 argumen, dapat 2",
+		`fungsi f(a, ...b) {} f();`:   "fungsi f mengharapkan paling sedikit 1 argumen, dapat 0",
+		`misal a = 1; a();`:           "fungsi call is not callable",
+		`cetak waktu_sekarang(1, 2);`: "fungsi waktu_sekarang mengharapkan 0 argumen, dapat 2",
+		`fungsi f(...a, b) {}`:        "rest parameter must be the last parameter",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func TestRestParameter(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi jumlah(awal, ...angka) {
+    misal total = awal;
+    misal i = 0;
+    selama i < panjang(angka) {
+        total = total + angka[i];
+        i = i + 1;
+    }
+    balikin total;
+}
+fungsi semua(...argumen) {
+    balikin argumen;
+}
+cetak jumlah(1);
+cetak jumlah(1, 2, 3);
+cetak semua();
+cetak semua("a", [1]);
+`)
+	compareOutput(t, "1\n6\n[]\n[\"a\", [1]]\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestDefaultAndNamedArguments(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal kali = 3;
+fungsi ulang(teks, jumlah = kali, pemisah = " ") {
+    misal hasil = teks;
+    misal i = 1;
+    selama i < jumlah {
+        hasil = hasil + pemisah + teks;
+        i = i + 1;
+    }
+    balikin hasil;
+}
+fungsi daftar(xs = [], ukuran = panjang(xs)) {
+    tambah(xs, ukuran);
+    balikin xs;
+}
+cetak ulang("a");
+cetak ulang("a", 2);
+cetak ulang("a", pemisah: "-");
+cetak ulang(pemisah: "+", teks: "b", jumlah: 2);
+kali = 1;
+cetak ulang("c");
+cetak daftar();
+cetak daftar();
+cetak daftar([7, 8]);
+`)
+	compareOutput(t, "a a a\na a\na-a-a\nb+b\nc\n[0]\n[0]\n[7, 8, 2]\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestArgumentErrors(t *testing.T) {
+	testcases := map[string]string{
+		`fungsi f(a, b = 1) {} f();`:           "fungsi f mengharapkan paling sedikit 1 argumen, dapat 0",
+		`fungsi f(a, b = 1) {} f(1, 2, 3);`:    "fungsi f mengharapkan paling banyak 2 argumen, dapat 3",
+		`fungsi f(a, b = 1) {} f(c: 1);`:       "fungsi f tidak punya parameter bernama c",
+		`fungsi f(a, b = 1) {} f(1, a: 2);`:    "fungsi f mendapat argumen a lebih dari sekali",
+		`fungsi f(a, b = 1) {} f(b: 1, b: 2);`: "fungsi f mendapat argumen b lebih dari sekali",
+		`fungsi f(a, b = 1) {} f(b: 2);`:       "fungsi f tidak mendapat argumen untuk parameter a",
+		`fungsi f(a, ...b) {} f(b: [1]);`:      "fungsi f tidak punya parameter bernama b",
+		`panjang(teks: "a");`:                  "fungsi panjang tidak menerima argumen bernama",
+		`fungsi f(a = 1, b) {}`:                "parameter without a default value can't follow one with a default value",
+		`fungsi f(...a = []) {}`:               "rest parameter can't have a default value",
+		`fungsi f(a) {} f(a: 1, 2);`:           "positional argument can't follow a named argument",
+		"fungsi f(a = 1 - \"x\") {}\nf();":     "[line 1] '-' - operands must be numbers",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func TestAnonymousFunctions(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi terapkan(xs, f) {
+    misal hasil = [];
+    misal i = 0;
+    selama i < panjang(xs) {
+        tambah(hasil, f(xs[i]));
+        i = i + 1;
+    }
+    balikin hasil;
+}
+misal faktor = 10;
+misal kali = fungsi (x) { balikin x * faktor; };
+cetak terapkan([1, 2], kali);
+cetak terapkan([1, 2], fungsi (x) => x + 1);
+cetak (fungsi (a, b = 2) => a * b)(4);
+cetak kali;
+fungsi (x) { cetak x; }("langsung");
+`)
+	compareOutput(t, "[10, 20]\n[2, 3]\n8\n<fungsi anonim>\nlangsung\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+
+	_, stdErr = runScript(t, `misal f = fungsi (x) => x; f();`)
+	if !strings.Contains(stdErr, "fungsi anonim mengharapkan 1 argumen, dapat 0") {
+		t.Fatalf("expected an arity error naming the anonymous function, got %q", stdErr)
+	}
+}
+
+func TestForLoops(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal jumlah = 0;
+untuk misal i = 1; i <= 4; i = i + 1 {
+    jumlah = jumlah + i;
+}
+cetak jumlah;
+
+misal fs = [];
+untuk misal i = 0; i < 3; i = i + 1 {
+    tambah(fs, fungsi () => i);
+}
+untuk f dalam fs {
+    cetak f();
+}
+
+misal j = 0;
+untuk ; j < 2; {
+    j = j + 1;
+}
+cetak j;
+
+untuk x dalam rentang(10, 0, -4) {
+    cetak x;
+}
+untuk huruf dalam "aé😀" {
+    cetak huruf;
+}
+untuk k dalam {"a": 1, "b": 2} {
+    cetak k;
+}
+misal xs = [1];
+untuk x dalam xs {
+    jika x < 3 { tambah(xs, x + 1); }
+}
+cetak xs;
+cetak rentang(3);
+cetak panjang(rentang(0, 10, 3));
+`)
+	compareOutput(t, "10\n0\n1\n2\n2\n10\n6\n2\na\né\n😀\na\nb\n[1, 2, 3]\nrentang(0, 3, 1)\n4\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestForLoopErrors(t *testing.T) {
+	testcases := map[string]string{
+		"untuk x dalam 5 {}":                                    "[line 1] 'untuk' - can't iterate over angka",
+		"cetak rentang(0, 1, 0);":                               "rentang step can't be 0",
+		"cetak rentang(0, 1/0);":                                "rentang can't be used on +Inf",
+		"cetak rentang(0, 1, 0/0);":                             "rentang can't be used on NaN",
+		"cetak rentang(0, 100000000000000000000000);":           "rentang has too many numbers",
+		"cetak panjang(rentang(0, 1, 0.00000000000000000001));": "rentang has too many numbers",
+		"cetak rentang(\"a\");":                                 "rentang can't be used on teks",
+		"cetak rentang(1, 2, 3, 4);":                            "rentang expects at most 3 arguments",
+		"untuk misal i = 0; i < 1 {}":                           "expect ';' after untuk condition",
+		"untuk x dalam [1] {}\ncetak x;":                        "Undefined variable x",
+		"untuk misal i = 0; salah; {}\ncetak i;":                "Undefined variable i",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func TestBreakAndContinue(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal n = 0;
+selama benar {
+    n = n + 1;
+    jika n == 2 { lanjut; }
+    jika n > 4 { berhenti; }
+    cetak n;
+}
+baris: untuk misal i = 0; i < 3; i = i + 1 {
+    untuk misal j = 0; j < 3; j = j + 1 {
+        jika j > i { lanjut baris; }
+        jika i == 2 { berhenti baris; }
+        cetak [i, j];
+    }
+}
+misal fs = [];
+untuk x dalam rentang(5) {
+    jika x == 1 { lanjut; }
+    tambah(fs, fungsi () => x);
+    jika x == 3 { berhenti; }
+}
+untuk f dalam fs { cetak f(); }
+fungsi cari(xs, dicari) {
+    untuk x dalam xs {
+        jika x == dicari { balikin benar; }
+    }
+    balikin salah;
+}
+cetak cari([1, 2], 2);
+`)
+	compareOutput(t, "1\n3\n4\n[0, 0]\n[1, 0]\n[1, 1]\n0\n2\n3\nbenar\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestElseIfChain(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi nilai(n) {
+    jika n >= 90 {
+        balikin "A";
+    } lain jika n >= 80 {
+        balikin "B";
+    } lain jika n >= 70 {
+        balikin "C";
+    } lain {
+        balikin "D";
+    }
+}
+untuk n dalam [95, 85, 75, 10] { cetak nilai(n); }
+jika salah { cetak 1; } lain jika salah { cetak 2; }
+cetak "selesai";
+`)
+	compareOutput(t, "A\nB\nC\nD\nselesai\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestClasses(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+kelas Titik {
+    inisialisasi(x, y = 0) {
+        ini.x = x;
+        ini.y = y;
+    }
+    geser(dx, dy) {
+        ini.x = ini.x + dx;
+        ini.y = ini.y + dy;
+        balikin ini;
+    }
+    jarakKuadrat() { balikin ini.x * ini.x + ini.y * ini.y; }
+    pembaca() { balikin fungsi () => ini.x; }
+}
+misal p = Titik(3, y: 4);
+cetak p;
+cetak p.jarakKuadrat();
+misal geser = p.geser;
+geser(1, 1);
+cetak p;
+cetak p.geser(1, 0).x;
+cetak p.pembaca()();
+cetak Titik;
+cetak geser;
+cetak [p];
+kelas Kosong {}
+misal k = Kosong();
+k.nama = "k";
+k.daftar = [1];
+k.daftar[0] = 2;
+cetak k;
+cetak p.inisialisasi(0);
+cetak p == p;
+cetak Titik(1) == Titik(1);
+`)
+	compareOutput(t, `Titik{x: 3, y: 4}
+25
+Titik{x: 4, y: 5}
+5
+5
+<kelas Titik>
+<fungsi geser>
+[Titik{x: 5, y: 5}]
+Kosong{nama: "k", daftar: [2]}
+Titik{x: 0, y: 0}
+benar
+salah
+`, stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestInheritance(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+kelas Hewan {
+    inisialisasi(nama) { ini.nama = nama; }
+    suara() { balikin "..."; }
+    bicara() { balikin ini.nama + " berkata " + ini.suara(); }
+}
+kelas Anjing < Hewan {
+    suara() { balikin "guk"; }
+    bicara() { balikin "[" + induk.bicara() + "]"; }
+}
+kelas Anak < Anjing {
+    inisialisasi(nama, umur) {
+        induk.inisialisasi(nama);
+        ini.umur = umur;
+    }
+    bicara() {
+        misal f = fungsi () => induk.bicara();
+        balikin f() + "!";
+    }
+}
+misal a = Anjing("rex");
+cetak a;
+cetak a.bicara();
+misal b = Anak("kecil", 1);
+cetak b;
+cetak b.bicara();
+misal suara = b.suara;
+cetak suara();
+`)
+	compareOutput(t, `Anjing{nama: "rex"}
+[rex berkata guk]
+Anak{nama: "kecil", umur: 1}
+[kecil berkata guk]!
+guk
+`, stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestClassErrors(t *testing.T) {
+	testcases := map[string]string{
+		"kelas A {}\ncetak A().b;":             "[line 2] 'b' - Undefined property b",
+		"misal a = 1; cetak a.b;":              "can't read property b of angka",
+		`misal a = "s"; a.b = 1;`:              "can't set property b on teks",
+		"kelas A { inisialisasi(a) {} }\nA();": "[line 2] ')' - fungsi A mengharapkan 1 argumen, dapat 0",
+		"kelas A {}\nA(1);":                    "fungsi A mengharapkan 0 argumen, dapat 1",
+		"kelas A { f() {} } cetak A.f;":        "can't read property f of kelas",
+		"kelas { }":                            "expect identifier after kelas declaration",
+		"kelas A { fungsi f() {} }":            "expect method name",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi bagi(a, b) {
+    jika b == 0 { lempar "pembagian dengan nol"; }
+    balikin a / b;
+}
+coba {
+    cetak bagi(1, 0);
+} tangkap (e) {
+    cetak e;
+    cetak e.pesan;
+    cetak e.baris;
+    cetak e.jejak;
+}
+coba { cetak x; } tangkap (e) { cetak [e.pesan, e.nilai, e.baris]; }
+coba { lempar {"kode": 1}; } tangkap (e) { cetak e.nilai["kode"]; }
+coba { lempar galat("buatan"); } tangkap (e) { cetak e; }
+coba {
+    coba { lempar 1; } tangkap (e) { lempar e; } akhirnya { cetak "akhirnya dalam"; }
+} tangkap (e) {
+    cetak e.baris;
+}
+coba {
+    coba { lempar 2; } akhirnya { cetak "tanpa tangkap"; }
+} tangkap (e) {
+    cetak e.nilai;
+}
+coba { panjang(1); } tangkap (e) { cetak e.pesan; }
+`)
+	compareOutput(t, `<galat pembagian dengan nol>
+pembagian dengan nol
+3
+["bagi baris 3", "<skrip> baris 7"]
+["Undefined variable x", kosong, 14]
+1
+<galat buatan>
+akhirnya dalam
+18
+tanpa tangkap
+2
+panjang can't be used on angka
+`, stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestFinallyRunsOnJumps(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi f() {
+    coba { balikin "coba"; } akhirnya { cetak "f akhirnya"; }
+}
+cetak f();
+fungsi g() {
+    coba { balikin 1; } akhirnya { balikin 2; }
+}
+cetak g();
+untuk i dalam rentang(4) {
+    coba {
+        jika i == 1 { lanjut; }
+        jika i == 2 { berhenti; }
+        cetak i;
+    } akhirnya {
+        cetak "akhirnya " + "i";
+    }
+}
+`)
+	compareOutput(t, "f akhirnya\ncoba\n2\n0\nakhirnya i\nakhirnya i\nakhirnya i\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	testcases := map[string]string{
+		"cetak 1;\nlempar \"gagal\";":                     "[line 2] 'lempar' - gagal",
+		"coba { lempar 1; } akhirnya { }\n":               "[line 1] 'lempar' - 1",
+		"coba { } tangkap (e) { }\ncetak e;":              "Undefined variable e",
+		"galat(1);":                                       "galat expects a teks message, got angka",
+		"coba { lempar 1; } tangkap (e) { e.pesan = 2; }": "can't set property pesan on galat",
+		"coba { }":               "expect tangkap or akhirnya after coba block",
+		"coba { } tangkap e { }": "expect opening '(' after tangkap",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal nama = "Budi";
+misal umur = 20;
+cetak "halo ${nama}, umur ${umur + 1}";
+cetak "${[1, "a"]} ${ {"k": kosong} } ${benar}${1.5}";
+cetak "luar ${"dalam ${umur * 2}"} \${nama} $umur";
+misal sapa = fungsi (x) => "hai ${x}!";
+cetak sapa(sapa("kamu"));
+`)
+	compareOutput(t, "halo Budi, umur 21\n[1, \"a\"] {\"k\": kosong} benar1.5\nluar dalam 40 ${nama} $umur\nhai hai kamu!!\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestStringInterpolationErrors(t *testing.T) {
+	testcases := map[string]string{
+		`cetak "a ${}";`:    "expect expression",
+		`cetak "a ${1 2}";`: "expect closing '}' after interpolated expression",
+		`cetak "a ${1}`:     "unterminated string",
+		`cetak "${x}";`:     "[line 1] 'x' - Undefined variable x",
+		`cetak "${-"a"}";`:  "operand must be a number",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal xs = [1, "dua", benar, kosong, [3]];
+cetak xs;
+cetak xs[1];
+cetak xs[4][0];
+xs[0] = xs[0] + 10;
+xs[4][0] = "tiga";
+cetak xs;
+cetak panjang(xs);
+
+misal ys = xs;
+tambah(ys, 5);
+cetak panjang(xs);
+
+cetak [];
+cetak [1, 2,];
+cetak [1, [2]] == [1, [2]];
+cetak [1, 2] == [2, 1];
+cetak "halo"[1];
+`)
+	compareOutput(t, "[1, \"dua\", benar, kosong, [3]]\ndua\n3\n[11, \"dua\", benar, kosong, [\"tiga\"]]\n5\n6\n[]\n[1, 2]\nbenar\nsalah\na\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestListErrors(t *testing.T) {
+	testcases := map[string]string{
+		"misal xs = [1];\ncetak xs[-1];":  "[line 2] ']' - negative index -1 is not allowed",
+		"misal xs = [1];\ncetak xs[1];":   "[line 2] ']' - index 1 is out of range for length 1",
+		"misal xs = [1];\nxs[3] = 1;":     "[line 2] ']' - index 3 is out of range for length 1",
+		"misal xs = [1];\ncetak xs[0.5];": "index must be a whole number",
+		`cetak 1[0];`:                     "can't index angka",
+		`misal a = 1; a[0] = 2;`:          "can't assign by index to angka",
+		`1 = 2;`:                          "invalid assignment target",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func TestMap(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal orang = {"nama": "budi", "umur": 30};
+cetak orang;
+cetak orang["nama"];
+orang["umur"] = orang["umur"] + 1;
+orang["kota"] = "bandung";
+cetak orang;
+cetak panjang(orang);
+cetak kunci(orang);
+
+cetak hapus(orang, "nama");
+cetak hapus(orang, "nama");
+orang["nama"] = "budi";
+cetak orang;
+cetak punya(orang, "umur");
+cetak punya(orang, "alamat");
+
+cetak {};
+cetak {1: "satu", benar: [1], "x": {"y": kosong},};
+cetak {"a": 1, "b": 2} == {"b": 2, "a": 1};
+cetak {"a": 1} == {"a": 2};
+{
+    cetak "blok";
+}
+`)
+	compareOutput(t, `{"nama": "budi", "umur": 30}
+budi
+{"nama": "budi", "umur": 31, "kota": "bandung"}
+3
+["nama", "umur", "kota"]
+benar
+salah
+{"umur": 31, "kota": "bandung", "nama": "budi"}
+benar
+salah
+{}
+{1: "satu", benar: [1], "x": {"y": kosong}}
+benar
+salah
+blok
+`, stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestSelfReferencingCollections(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+misal xs = [1];
+tambah(xs, xs);
+misal ys = [1];
+tambah(ys, ys);
+cetak xs;
+cetak xs == xs;
+cetak xs == ys;
+cetak xs == [1, [1]];
+misal m = {"a": 1};
+m["diri"] = m;
+misal n = {"a": 1};
+n["diri"] = n;
+cetak m;
+cetak m == m;
+cetak m == n;
+cetak [m, m];
+`)
+	compareOutput(t, "[1, [...]]\nbenar\nbenar\nsalah\n{\"a\": 1, \"diri\": {...}}\nbenar\nbenar\n"+
+		"[{\"a\": 1, \"diri\": {...}}, {\"a\": 1, \"diri\": {...}}]\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestMapErrors(t *testing.T) {
+	testcases := map[string]string{
+		"misal m = {\"a\": 1};\ncetak m[\"b\"];": `[line 2] ']' - key "b" not found`,
+		`misal m = {[1]: 1};`:                    "daftar can't be used as a map key",
+		`misal m = {}; m[{}] = 1;`:               "kamus can't be used as a map key",
+		`misal m = {}; m[0/0] = 1;`:              "[line 1] ']' - NaN can't be used as a map key",
+		`misal m = {0/0: 1};`:                    "NaN can't be used as a map key",
+		`misal m = {"a" 1};`:                     "expect ':' between map key and value",
+		`kunci([1]);`:                            "kunci can't be used on daftar",
+		`punya({}, [1]);`:                        "[line 1] ')' - daftar can't be used as a map key",
+		`punya({}, 0/0);`:                        "NaN can't be used as a map key",
+		`hapus({}, [1]);`:                        "daftar can't be used as a map key",
+		`hapus({}, 0/0);`:                        "NaN can't be used as a map key",
+	}
+	for testcase, expected := range testcases {
+		_, stdErr := runScript(t, testcase)
+		if !strings.Contains(stdErr, expected) {
+			t.Fatalf("expected %q in stdErr for %q, got %q", expected, testcase, stdErr)
+		}
+	}
+}
+
+func TestHookSeesFrames(t *testing.T) {
+	source := "misal a = 1;\nfungsi f(x) {\n    balikin x + a;\n}\ncetak f(2);\n"
+	tokens := lexer.NewScanner(strings.NewReader(source), io.Discard).ScanTokens()
+	stmts, _ := ast.NewParser(tokens, io.Discard).Parse()
+	resolver.NewResolver(io.Discard).Resolve(stmts)
+
+	var trace []string
+	interpreter := NewInterpreter(io.Discard, io.Discard)
+	interpreter.SetHook(func(stmt ast.Stmt, frames []Frame) {
+		var names []string
+		for _, frame := range frames {
+			names = append(names, fmt.Sprintf("%s:%d", frame.Name, frame.Line()))
+		}
+		trace = append(trace, strings.Join(names, " "))
+	})
+	if err := interpreter.Interpret(stmts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{":1", ":2", ":5", ":5 f:3"}
+	if strings.Join(trace, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v while actual is %v", expected, trace)
+	}
+}
+
+func TestReturnUnwindsLoopsAndBlocks(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi cari(batas) {
+    misal i = 0;
+    selama benar {
+        {
+            jika i == batas {
+                balikin i;
+            }
+        }
+        i = i + 1;
+    }
+}
+cetak cari(3);
+misal a = "global";
+cetak a;
+`)
+	compareOutput(t, "3\nglobal\n", stdOut)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestRuntimeErrorStopsScript(t *testing.T) {
+	stdOut, stdErr := runScript(t, `
+fungsi f() {
+    selama benar {
+        cetak "sekali";
+        balikin 1 - "a";
+    }
+}
+f();
+cetak "tidak dicetak";
+`)
+	compareOutput(t, "sekali\n", stdOut)
+	if !strings.Contains(stdErr, "[line 5] '-' - operands must be numbers") {
+		t.Fatalf("expected the error on line 5 while actual is %q", stdErr)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	_, stdErr := runScript(t, `
+fungsi f(n) {
+    balikin f(n + 1);
+}
+f(0);
+`)
+	if !strings.Contains(stdErr, "stack overflow") {
+		t.Fatalf("expected stack overflow while actual is %q", stdErr)
+	}
+}
+
+func BenchmarkRecursion(b *testing.B) {
+	benchmarkScript(b, `
+fungsi faktorial(n) {
+    jika n < 2 { balikin 1; }
+    balikin n * faktorial(n - 1);
+}
+faktorial(100);
+`)
+}
+
+// BenchmarkReturnFromLoop returns from inside a loop and a jika block, a
+// balikin there used to be a panic unwinding every block it left.
+func BenchmarkReturnFromLoop(b *testing.B) {
+	benchmarkScript(b, `
+fungsi cari(n) {
+    misal i = 0;
+    selama benar {
+        jika i == n { balikin i; }
+        i = i + 1;
+    }
+}
+misal k = 0;
+selama k < 1000 { cari(3); k = k + 1; }
+`)
+}
+
+func benchmarkScript(b *testing.B, source string) {
+	tokens := lexer.NewScanner(strings.NewReader(source), io.Discard).ScanTokens()
+	stmts, _ := ast.NewParser(tokens, io.Discard).Parse()
+	resolver.NewResolver(io.Discard).Resolve(stmts)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := NewInterpreter(io.Discard, io.Discard).Interpret(stmts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// Range is the value returned by rentang, the numbers from Start up to but not
+// including End, Step apart. A negative Step counts down.
+type Range struct {
+	Start float64
+	End   float64
+	Step  float64
+	// count is how many numbers the range produces
+	count int
+}
+
+// NewRange checks that the range can be counted, its bounds and step must be
+// finite and it can't produce more than math.MaxInt numbers.
+func NewRange(start, end, step float64) (*Range, error) {
+	if step == 0 {
+		return nil, fmt.Errorf("rentang step can't be 0")
+	}
+	for _, number := range []float64{start, end, step} {
+		if math.IsInf(number, 0) || math.IsNaN(number) {
+			return nil, fmt.Errorf("rentang can't be used on %s", Stringify(number))
+		}
+	}
+	count := math.Ceil((end - start) / step)
+	if count >= math.MaxInt {
+		return nil, fmt.Errorf("rentang has too many numbers")
+	}
+	return &Range{Start: start, End: end, Step: step, count: max(int(count), 0)}, nil
+}
+
+// Len returns how many numbers the range produces.
+func (r *Range) Len() int {
+	return r.count
+}
+
+func (r *Range) String() string {
+	return fmt.Sprintf("rentang(%s, %s, %s)", Stringify(r.Start), Stringify(r.End), Stringify(r.Step))
+}
+
+// Iterator walks the elements an untuk ... dalam loop visits.
+type Iterator struct {
+	next func() (any, bool)
+}
+
+// Next returns the next element, the second result is false once there are no
+// elements left.
+func (it *Iterator) Next() (any, bool) {
+	return it.next()
+}
+
+// NewIterator iterates over ranges, lists by their elements, strings by their
+// characters and maps by their keys. A list is read as it is iterated, so
+// elements appended by the loop body are visited too, while a map's keys are
+// taken when the loop starts.
+func NewIterator(value any) (*Iterator, error) {
+	switch v := value.(type) {
+	case *Range:
+		position, count := 0, v.Len()
+		return &Iterator{next: func() (any, bool) {
+			if position >= count {
+				return nil, false
+			}
+			element := v.Start + float64(position)*v.Step
+			position++
+			return element, true
+		}}, nil
+	case *List:
+		position := 0
+		return &Iterator{next: func() (any, bool) {
+			if position >= len(v.Elements) {
+				return nil, false
+			}
+			position++
+			return v.Elements[position-1], true
+		}}, nil
+	case string:
+		offset := 0
+		return &Iterator{next: func() (any, bool) {
+			if offset >= len(v) {
+				return nil, false
+			}
+			_, size := utf8.DecodeRuneInString(v[offset:])
+			offset += size
+			return v[offset-size : offset], true
+		}}, nil
+	case *Map:
+		keys, position := v.Keys(), 0
+		return &Iterator{next: func() (any, bool) {
+			if position >= len(keys) {
+				return nil, false
+			}
+			position++
+			return keys[position-1], true
+		}}, nil
+	}
+	return nil, fmt.Errorf("can't iterate over %s", TypeName(value))
+}
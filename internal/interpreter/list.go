@@ -0,0 +1,38 @@
+package interpreter
+
+import (
+	"strings"
+)
+
+// List is the runtime value of a list literal. Lists are shared by reference,
+// assigning a list to another variable doesn't copy its elements.
+type List struct {
+	Elements []any
+}
+
+func NewList(elements []any) *List {
+	return &List{Elements: elements}
+}
+
+func (l *List) String() string {
+	return l.format(nil)
+}
+
+func (l *List) format(printing map[any]bool) string {
+	if printing[l] {
+		return "[...]"
+	}
+	printing = enter(printing, l)
+	defer delete(printing, l)
+
+	var builder strings.Builder
+	builder.WriteByte('[')
+	for i, element := range l.Elements {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(inspect(element, printing))
+	}
+	builder.WriteByte(']')
+	return builder.String()
+}
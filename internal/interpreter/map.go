@@ -0,0 +1,99 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Map is the runtime value of a map literal. Maps remember the order their
+// keys were first inserted in: iterating and printing a map always follow that
+// order, assigning to an existing key keeps its position, and a deleted key
+// that is inserted again moves to the end. Like lists, maps are shared by
+// reference.
+type Map struct {
+	keys   []any
+	values map[any]any
+}
+
+func NewMap() *Map {
+	return &Map{values: make(map[any]any)}
+}
+
+func (m *Map) Get(key any) (any, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+func (m *Map) Set(key any, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *Map) Delete(key any) bool {
+	if _, ok := m.values[key]; !ok {
+		return false
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Keys returns the keys in insertion order.
+func (m *Map) Keys() []any {
+	keys := make([]any, len(m.keys))
+	copy(keys, m.keys)
+	return keys
+}
+
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+func (m *Map) String() string {
+	return m.format(nil)
+}
+
+func (m *Map) format(printing map[any]bool) string {
+	if printing[m] {
+		return "{...}"
+	}
+	printing = enter(printing, m)
+	defer delete(printing, m)
+
+	var builder strings.Builder
+	builder.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(inspect(key, printing))
+		builder.WriteString(": ")
+		builder.WriteString(inspect(m.values[key], printing))
+	}
+	builder.WriteByte('}')
+	return builder.String()
+}
+
+// CheckKey reports whether value can be used as a map key, only values that
+// compare by content are allowed. NaN isn't, it never equals itself so an
+// entry stored under it could never be found again.
+func CheckKey(key any) error {
+	switch k := key.(type) {
+	case float64:
+		if math.IsNaN(k) {
+			return fmt.Errorf("NaN can't be used as a map key")
+		}
+		return nil
+	case nil, bool, string:
+		return nil
+	}
+	return fmt.Errorf("%s can't be used as a map key", TypeName(key))
+}
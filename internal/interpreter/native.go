@@ -0,0 +1,125 @@
+package interpreter
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// NativeFunction is a callable implemented in Go. Hosts define them in the
+// global environment to give scripts access to their own helpers.
+type NativeFunction struct {
+	Name string
+	// NumArguments is the number of arguments Fn expects, or the minimum
+	// number of arguments when Variadic is set.
+	NumArguments int
+	Variadic     bool
+	Fn           func(arguments []any) (any, error)
+}
+
+func (n *NativeFunction) Arity() (int, bool) {
+	return n.NumArguments, n.Variadic
+}
+
+func (n *NativeFunction) Call(interpreter *Interpreter, arguments []any) (any, error) {
+	return n.Fn(arguments)
+}
+
+func (n *NativeFunction) String() string {
+	return "<fungsi bawaan " + n.Name + ">"
+}
+
+func NewNativeFunction(name string, arity int, fn func(arguments []any) (any, error)) *NativeFunction {
+	return &NativeFunction{Name: name, NumArguments: arity, Fn: fn}
+}
+
+func NewVariadicNativeFunction(name string, minArity int, fn func(arguments []any) (any, error)) *NativeFunction {
+	return &NativeFunction{Name: name, NumArguments: minArity, Variadic: true, Fn: fn}
+}
+
+// Builtins returns the native functions every script starts with.
+func Builtins() []*NativeFunction {
+	return builtins
+}
+
+var builtins = []*NativeFunction{
+	NewNativeFunction("panjang", 1, func(arguments []any) (any, error) {
+		switch v := arguments[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case *List:
+			return float64(len(v.Elements)), nil
+		case *Map:
+			return float64(v.Len()), nil
+		case *Range:
+			return float64(v.Len()), nil
+		}
+		return nil, fmt.Errorf("panjang can't be used on %s", TypeName(arguments[0]))
+	}),
+	NewNativeFunction("tambah", 2, func(arguments []any) (any, error) {
+		list, ok := arguments[0].(*List)
+		if !ok {
+			return nil, fmt.Errorf("tambah can't be used on %s", TypeName(arguments[0]))
+		}
+		list.Elements = append(list.Elements, arguments[1])
+		return nil, nil
+	}),
+	NewNativeFunction("kunci", 1, func(arguments []any) (any, error) {
+		m, ok := arguments[0].(*Map)
+		if !ok {
+			return nil, fmt.Errorf("kunci can't be used on %s", TypeName(arguments[0]))
+		}
+		return NewList(m.Keys()), nil
+	}),
+	NewNativeFunction("punya", 2, func(arguments []any) (any, error) {
+		m, ok := arguments[0].(*Map)
+		if !ok {
+			return nil, fmt.Errorf("punya can't be used on %s", TypeName(arguments[0]))
+		}
+		if err := CheckKey(arguments[1]); err != nil {
+			return nil, err
+		}
+		_, found := m.Get(arguments[1])
+		return found, nil
+	}),
+	NewNativeFunction("hapus", 2, func(arguments []any) (any, error) {
+		m, ok := arguments[0].(*Map)
+		if !ok {
+			return nil, fmt.Errorf("hapus can't be used on %s", TypeName(arguments[0]))
+		}
+		if err := CheckKey(arguments[1]); err != nil {
+			return nil, err
+		}
+		return m.Delete(arguments[1]), nil
+	}),
+	NewVariadicNativeFunction("rentang", 1, func(arguments []any) (any, error) {
+		if len(arguments) > 3 {
+			return nil, fmt.Errorf("rentang expects at most 3 arguments")
+		}
+		numbers := make([]float64, len(arguments))
+		for i, argument := range arguments {
+			number, ok := argument.(float64)
+			if !ok {
+				return nil, fmt.Errorf("rentang can't be used on %s", TypeName(argument))
+			}
+			numbers[i] = number
+		}
+		switch len(numbers) {
+		case 1:
+			return NewRange(0, numbers[0], 1)
+		case 2:
+			return NewRange(numbers[0], numbers[1], 1)
+		}
+		return NewRange(numbers[0], numbers[1], numbers[2])
+	}),
+	NewNativeFunction("waktu_sekarang", 0, func(arguments []any) (any, error) {
+		return float64(time.Now().UnixNano()) / float64(time.Second), nil
+	}),
+	NewNativeFunction("galat", 1, func(arguments []any) (any, error) {
+		message, ok := arguments[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("galat expects a teks message, got %s", TypeName(arguments[0]))
+		}
+		return NewException(message), nil
+	}),
+}
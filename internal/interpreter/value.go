@@ -0,0 +1,237 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// The helpers below define how runtime values behave, independent of the
+// backend running the script.
+
+func Stringify(value any) string {
+	return stringify(value, nil)
+}
+
+// stringify formats value, printing holds the lists, maps and objeks whose
+// contents are being formatted around it so one that contains itself prints
+// as a placeholder instead of recursing forever.
+func stringify(value any, printing map[any]bool) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case bool:
+		if v {
+			return "benar"
+		}
+		return "salah"
+	case nil:
+		return "kosong"
+	case *List:
+		return v.format(printing)
+	case *Map:
+		return v.format(printing)
+	case *Instance:
+		return v.format(printing)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return "unknown value"
+}
+
+// enter marks value as being printed, the set is created on first use.
+func enter(printing map[any]bool, value any) map[any]bool {
+	if printing == nil {
+		printing = make(map[any]bool)
+	}
+	printing[value] = true
+	return printing
+}
+
+// Inspect formats a value nested inside a collection, strings are quoted there
+// so ["1"] and [1] print differently.
+func Inspect(value any) string {
+	return inspect(value, nil)
+}
+
+func inspect(value any, printing map[any]bool) string {
+	if v, ok := value.(string); ok {
+		return strconv.Quote(v)
+	}
+	return stringify(value, printing)
+}
+
+func IsTruthy(value any) bool {
+	switch v := value.(type) {
+	case float64:
+		return v != 0.0
+	case string:
+		return v != ""
+	case bool:
+		return v
+	default:
+		return false
+	}
+}
+
+// IsEqual compares lists element by element, maps by their entries regardless
+// of order and every other value by identity, every other runtime value is
+// either a comparable primitive or a pointer.
+func IsEqual(left, right any) bool {
+	return isEqual(left, right, nil)
+}
+
+// comparison is a pair of collections being compared.
+type comparison struct {
+	left, right any
+}
+
+// isEqual compares left and right, comparing holds the pairs of collections
+// whose elements are being compared around them. A pair met again is taken as
+// equal, any difference shows up where the pair was first compared, so
+// collections containing themselves compare without recursing forever.
+func isEqual(left, right any, comparing map[comparison]bool) bool {
+	if left == right {
+		return true
+	}
+	pair := comparison{left, right}
+	if comparing[pair] {
+		return true
+	}
+
+	leftMap, leftIsMap := left.(*Map)
+	rightMap, rightIsMap := right.(*Map)
+	if leftIsMap && rightIsMap {
+		if leftMap.Len() != rightMap.Len() {
+			return false
+		}
+		comparing = compare(comparing, pair)
+		for _, key := range leftMap.keys {
+			rightValue, ok := rightMap.Get(key)
+			if !ok || !isEqual(leftMap.values[key], rightValue, comparing) {
+				return false
+			}
+		}
+		return true
+	}
+
+	leftList, leftIsList := left.(*List)
+	rightList, rightIsList := right.(*List)
+	if leftIsList && rightIsList {
+		if len(leftList.Elements) != len(rightList.Elements) {
+			return false
+		}
+		comparing = compare(comparing, pair)
+		for i := range leftList.Elements {
+			if !isEqual(leftList.Elements[i], rightList.Elements[i], comparing) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compare marks pair as being compared, the set is created on first use.
+func compare(comparing map[comparison]bool, pair comparison) map[comparison]bool {
+	if comparing == nil {
+		comparing = make(map[comparison]bool)
+	}
+	comparing[pair] = true
+	return comparing
+}
+
+// TypeName names the type of a runtime value for error messages.
+func TypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "kosong"
+	case float64:
+		return "angka"
+	case string:
+		return "teks"
+	case bool:
+		return "boolean"
+	case *List:
+		return "daftar"
+	case *Map:
+		return "kamus"
+	case *Range:
+		return "rentang"
+	case *Instance:
+		return "objek"
+	case Class:
+		return "kelas"
+	case *Exception:
+		return "galat"
+	case interface{ Arity() (int, bool) }:
+		return "fungsi"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// Index reads object[index] for lists, strings and maps.
+func Index(object, index any) (any, error) {
+	switch v := object.(type) {
+	case *List:
+		position, err := checkIndex(index, len(v.Elements))
+		if err != nil {
+			return nil, err
+		}
+		return v.Elements[position], nil
+	case string:
+		runes := []rune(v)
+		position, err := checkIndex(index, len(runes))
+		if err != nil {
+			return nil, err
+		}
+		return string(runes[position]), nil
+	case *Map:
+		if err := CheckKey(index); err != nil {
+			return nil, err
+		}
+		value, ok := v.Get(index)
+		if !ok {
+			return nil, fmt.Errorf("key %s not found", Inspect(index))
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("can't index %s", TypeName(object))
+}
+
+// SetIndex assigns object[index] = value for lists and maps.
+func SetIndex(object, index, value any) error {
+	switch v := object.(type) {
+	case *List:
+		position, err := checkIndex(index, len(v.Elements))
+		if err != nil {
+			return err
+		}
+		v.Elements[position] = value
+		return nil
+	case *Map:
+		if err := CheckKey(index); err != nil {
+			return err
+		}
+		v.Set(index, value)
+		return nil
+	}
+	return fmt.Errorf("can't assign by index to %s", TypeName(object))
+}
+
+// checkIndex makes sure index is a whole number inside [0, length)
+func checkIndex(index any, length int) (int, error) {
+	number, ok := index.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, fmt.Errorf("index must be a whole number")
+	}
+	if number < 0 {
+		return 0, fmt.Errorf("negative index %d is not allowed", int(number))
+	}
+	if number >= float64(length) {
+		return 0, fmt.Errorf("index %d is out of range for length %d", int(number), length)
+	}
+	return int(number), nil
+}
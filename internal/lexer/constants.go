@@ -1,18 +1,43 @@
 package lexer
 
-import "github.com/aselhid/indoscript/internal/ast"
+import (
+	"sort"
+
+	"github.com/aselhid/indoscript/internal/ast"
+)
 
 var keywords = map[string]ast.TokenType{
-	"misal":   ast.TokenLet,
-	"jika":    ast.TokenIf,
-	"lain":    ast.TokenElse,
-	"fungsi":  ast.TokenFunction,
-	"balikin": ast.TokenReturn,
-	"kosong":  ast.TokenNil,
-	"benar":   ast.TokenTrue,
-	"salah":   ast.TokenFalse,
-	"selama":  ast.TokenLoop,
-	"cetak":   ast.TokenPrint,
-	"dan":     ast.TokenAnd,
-	"atau":    ast.TokenOr,
+	"misal":    ast.TokenLet,
+	"jika":     ast.TokenIf,
+	"lain":     ast.TokenElse,
+	"fungsi":   ast.TokenFunction,
+	"balikin":  ast.TokenReturn,
+	"kosong":   ast.TokenNil,
+	"benar":    ast.TokenTrue,
+	"salah":    ast.TokenFalse,
+	"selama":   ast.TokenLoop,
+	"cetak":    ast.TokenPrint,
+	"dan":      ast.TokenAnd,
+	"atau":     ast.TokenOr,
+	"untuk":    ast.TokenFor,
+	"dalam":    ast.TokenIn,
+	"berhenti": ast.TokenBreak,
+	"lanjut":   ast.TokenContinue,
+	"kelas":    ast.TokenClass,
+	"ini":      ast.TokenThis,
+	"induk":    ast.TokenSuper,
+	"lempar":   ast.TokenThrow,
+	"coba":     ast.TokenTry,
+	"tangkap":  ast.TokenCatch,
+	"akhirnya": ast.TokenFinally,
+}
+
+// Keywords returns every reserved word in alphabetical order.
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
@@ -8,6 +8,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
 )
 
 type Reader struct {
@@ -28,30 +29,58 @@ func (r *Reader) PeekRune() rune {
 }
 
 type Scanner struct {
-	reader     *Reader
-	tokens     []ast.Token
-	buffer     []rune
-	lineNumber int
-	stdErr     io.Writer
+	reader *Reader
+	tokens []ast.Token
+	// buffer holds every rune consumed since the current token started
+	buffer []rune
+	// position is where the next rune will be read from, start is where
+	// the current token started
+	position diagnostic.Position
+	start    diagnostic.Position
+	stdErr   io.Writer
+	errors   []error
+	// templates holds, for every interpolated expression being scanned, the
+	// number of '{' opened inside it and not closed yet
+	templates []int
 }
 
 func NewScanner(r io.Reader, stdErr io.Writer) *Scanner {
 	return &Scanner{
-		reader:     &Reader{bufio.NewReader(r)},
-		stdErr:     stdErr,
-		lineNumber: 1,
+		reader:   &Reader{bufio.NewReader(r)},
+		stdErr:   stdErr,
+		position: diagnostic.Position{Line: 1, Column: 1},
 	}
 }
 
 func (s *Scanner) ScanTokens() []ast.Token {
+	var tokens []ast.Token
+	for _, token := range s.ScanTokensWithComments() {
+		if token.TokenType != ast.TokenComment {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// ScanTokensWithComments is like ScanTokens but also keeps a TokenComment for
+// every comment, so together with the token positions the source can be
+// reproduced from the tokens.
+func (s *Scanner) ScanTokensWithComments() []ast.Token {
 	for !s.isAtEnd() {
+		s.start = s.position
 		s.scanToken()
 		s.clearBuffer()
 	}
-	s.tokens = append(s.tokens, ast.Token{TokenType: ast.TokenEof, LineNumber: s.lineNumber})
+	s.start = s.position
+	s.tokens = append(s.tokens, s.newToken(ast.TokenEof, nil))
 	return s.tokens
 }
 
+// Errors returns every error reported while scanning.
+func (s *Scanner) Errors() []error {
+	return s.errors
+}
+
 func (s *Scanner) scanToken() {
 	char := s.advance()
 	switch char {
@@ -60,19 +89,45 @@ func (s *Scanner) scanToken() {
 	case ')':
 		s.addToken(ast.TokenRightParenthesis)
 	case '{':
+		if len(s.templates) > 0 {
+			s.templates[len(s.templates)-1]++
+		}
 		s.addToken(ast.TokenLeftBrace)
 	case '}':
-		s.addToken(ast.TokenRightBrace)
+		top := len(s.templates) - 1
+		switch {
+		case top < 0:
+			s.addToken(ast.TokenRightBrace)
+		case s.templates[top] > 0:
+			s.templates[top]--
+			s.addToken(ast.TokenRightBrace)
+		default:
+			// the '}' closing an interpolated expression resumes its string
+			s.templates = s.templates[:top]
+			s.stringSegment(true)
+		}
+	case '[':
+		s.addToken(ast.TokenLeftBracket)
+	case ']':
+		s.addToken(ast.TokenRightBracket)
 	case ',':
 		s.addToken(ast.TokenComma)
 	case '.':
-		s.addToken(ast.TokenDot)
+		if !s.match('.') {
+			s.addToken(ast.TokenDot)
+		} else if s.match('.') {
+			s.addToken(ast.TokenEllipsis)
+		} else {
+			s.error("found unexpected \"..\", did you mean \"...\"")
+		}
 	case '+':
 		s.addToken(ast.TokenPlus)
 	case '-':
 		s.addToken(ast.TokenMinus)
 	case ';':
 		s.addToken(ast.TokenSemicolon)
+	case ':':
+		s.addToken(ast.TokenColon)
 	case '*':
 		s.addToken(ast.TokenStar)
 
@@ -84,12 +139,15 @@ func (s *Scanner) scanToken() {
 				}
 				s.advance()
 			}
+			s.addToken(ast.TokenComment)
 		} else {
 			s.addToken(ast.TokenSlash)
 		}
 	case '=':
 		if s.match('=') {
 			s.addToken(ast.TokenEqualEqual)
+		} else if s.match('>') {
+			s.addToken(ast.TokenArrow)
 		} else {
 			s.addToken(ast.TokenEqual)
 		}
@@ -118,18 +176,16 @@ func (s *Scanner) scanToken() {
 	case '\r':
 
 	case '\n':
-		s.lineNumber++
 
 	case '"':
-		s.buffer = []rune{char}
 		s.string()
+	case '`':
+		s.rawString()
 
 	default:
 		if s.isDigit(char) {
-			s.buffer = []rune{char}
 			s.number()
 		} else if s.isAllowedAlpha(char) {
-			s.buffer = []rune{char}
 			s.identifierOrKeyword()
 		} else {
 			s.error(fmt.Sprintf("found unexpected character  \"%c\"", char))
@@ -138,9 +194,46 @@ func (s *Scanner) scanToken() {
 	}
 }
 
+// string scans a string between double quotes, it ends on the line it starts
+// on and its escape sequences are replaced by the runes they stand for.
+//
+// A string holding ${...} is scanned as template segments instead: the
+// TokenTemplateStart up to the first "${", then the tokens of the expression,
+// then a TokenTemplateMiddle from its "}" up to the next "${" or the
+// TokenTemplateEnd from its "}" up to the closing quote.
 func (s *Scanner) string() {
-	for s.reader.PeekRune() != '"' && s.reader.PeekRune() != '\n' {
-		s.buffer = append(s.buffer, s.advance())
+	s.stringSegment(false)
+}
+
+// stringSegment scans a string up to its closing quote or the next "${",
+// resumed tells whether it continues after the "}" of an interpolated
+// expression.
+func (s *Scanner) stringSegment(resumed bool) {
+	var value []rune
+	for !s.isAtEnd() && s.reader.PeekRune() != '"' && s.reader.PeekRune() != '\n' {
+		switch s.reader.PeekRune() {
+		case '\\':
+			start := s.position
+			s.advance()
+			if r, ok := s.escape(start); ok {
+				value = append(value, r)
+			}
+		case '$':
+			s.advance()
+			if !s.match('{') {
+				value = append(value, '$')
+				continue
+			}
+			s.templates = append(s.templates, 0)
+			tokenType := ast.TokenTemplateStart
+			if resumed {
+				tokenType = ast.TokenTemplateMiddle
+			}
+			s.addTokenWithLiteral(tokenType, string(value))
+			return
+		default:
+			value = append(value, s.advance())
+		}
 	}
 
 	if s.isAtEnd() || s.reader.PeekRune() == '\n' {
@@ -148,20 +241,100 @@ func (s *Scanner) string() {
 		return
 	}
 
-	s.buffer = append(s.buffer, s.advance())
-	value := string(s.buffer[1 : len(s.buffer)-1])
-	s.addTokenWithLiteral(ast.TokenString, value)
+	s.advance()
+	tokenType := ast.TokenString
+	if resumed {
+		tokenType = ast.TokenTemplateEnd
+	}
+	s.addTokenWithLiteral(tokenType, string(value))
+}
+
+// escape scans an escape sequence whose backslash, at start, was just
+// consumed. An invalid sequence is reported and scanning goes on after it.
+func (s *Scanner) escape(start diagnostic.Position) (rune, bool) {
+	// a backslash ending the line is left for string to report
+	if s.isAtEnd() || s.reader.PeekRune() == '\n' {
+		return 0, false
+	}
+
+	char := s.advance()
+	switch char {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case 'r':
+		return '\r', true
+	case '"':
+		return '"', true
+	case '$':
+		return '$', true
+	case '\\':
+		return '\\', true
+	case 'u':
+		return s.unicodeEscape(start)
+	}
+	s.errorAt(start, fmt.Sprintf("invalid escape sequence \"\\%c\"", char))
+	return 0, false
+}
+
+// unicodeEscape scans the \u{...} escape sequence after its 'u', which holds
+// the code point in 1 to 6 hexadecimal digits.
+func (s *Scanner) unicodeEscape(start diagnostic.Position) (rune, bool) {
+	if !s.match('{') {
+		s.errorAt(start, "expect '{' after \"\\u\"")
+		return 0, false
+	}
+
+	var digits []rune
+	for s.isHexDigit(s.reader.PeekRune()) {
+		digits = append(digits, s.advance())
+	}
+	if !s.match('}') || len(digits) == 0 || len(digits) > 6 {
+		s.errorAt(start, "expect 1 to 6 hexadecimal digits between \"\\u{\" and \"}\"")
+		return 0, false
+	}
+
+	code, _ := strconv.ParseUint(string(digits), 16, 32)
+	if r := rune(code); utf8.ValidRune(r) {
+		return r, true
+	}
+	s.errorAt(start, fmt.Sprintf("invalid unicode code point \"%s\"", string(digits)))
+	return 0, false
+}
+
+// rawString scans a string between backticks, it may span lines and has no
+// escape sequences. Carriage returns are dropped so a script reads the same
+// with either line ending.
+func (s *Scanner) rawString() {
+	for !s.isAtEnd() && s.reader.PeekRune() != '`' {
+		s.advance()
+	}
+
+	if s.isAtEnd() {
+		s.report(s.start, diagnostic.CodeUnterminatedRawString, "unterminated raw string")
+		return
+	}
+
+	s.advance()
+	var value []rune
+	for _, r := range s.buffer[1 : len(s.buffer)-1] {
+		if r != '\r' {
+			value = append(value, r)
+		}
+	}
+	s.addTokenWithLiteral(ast.TokenString, string(value))
 }
 
 func (s *Scanner) number() {
 	for s.isDigit(s.reader.PeekRune()) {
-		s.buffer = append(s.buffer, s.advance())
+		s.advance()
 	}
 
 	if r := s.reader.PeekRune(); r == '.' {
-		s.buffer = append(s.buffer, s.advance())
+		s.advance()
 		for s.isDigit(s.reader.PeekRune()) {
-			s.buffer = append(s.buffer, s.advance())
+			s.advance()
 		}
 	}
 
@@ -171,7 +344,7 @@ func (s *Scanner) number() {
 
 func (s *Scanner) identifierOrKeyword() {
 	for s.isAllowedAlphanumeric(s.reader.PeekRune()) {
-		s.buffer = append(s.buffer, s.advance())
+		s.advance()
 	}
 
 	text := string(s.buffer)
@@ -184,16 +357,23 @@ func (s *Scanner) identifierOrKeyword() {
 
 // TODO: handle error
 func (s *Scanner) advance() rune {
-	char, _, _ := s.reader.ReadRune()
+	char, size, _ := s.reader.ReadRune()
+	s.buffer = append(s.buffer, char)
+	s.position.Offset += size
+	if char == '\n' {
+		s.position.Line++
+		s.position.Column = 1
+	} else {
+		s.position.Column++
+	}
 	return char
 }
 
 func (s *Scanner) match(target rune) bool {
-	char, _, _ := s.reader.ReadRune()
-	if target != char {
-		s.reader.UnreadRune()
+	if s.reader.PeekRune() != target {
 		return false
 	}
+	s.advance()
 	return true
 }
 
@@ -207,11 +387,22 @@ func (s *Scanner) addToken(tokenType ast.TokenType) {
 }
 
 func (s *Scanner) addTokenWithLiteral(tokenType ast.TokenType, literal any) {
+	s.tokens = append(s.tokens, s.newToken(tokenType, literal))
+}
+
+func (s *Scanner) newToken(tokenType ast.TokenType, literal any) ast.Token {
 	var lexeme string
 	if len(s.buffer) > 0 {
 		lexeme = string(s.buffer)
 	}
-	s.tokens = append(s.tokens, ast.Token{TokenType: tokenType, LineNumber: s.lineNumber, Literal: literal, Lexeme: lexeme})
+	return ast.Token{
+		TokenType:  tokenType,
+		LineNumber: s.start.Line,
+		Column:     s.start.Column,
+		Offset:     s.start.Offset,
+		Literal:    literal,
+		Lexeme:     lexeme,
+	}
 }
 
 func (s *Scanner) clearBuffer() {
@@ -222,6 +413,10 @@ func (s *Scanner) isDigit(r rune) bool {
 	return r >= '0' && r <= '9'
 }
 
+func (s *Scanner) isHexDigit(r rune) bool {
+	return s.isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
 func (s *Scanner) isAllowedAlpha(r rune) bool {
 	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
 }
@@ -230,6 +425,21 @@ func (s *Scanner) isAllowedAlphanumeric(r rune) bool {
 	return s.isAllowedAlpha(r) || s.isDigit(r)
 }
 
+// error reports a problem with the runes consumed since the current token
+// started.
 func (s *Scanner) error(message string) {
-	s.stdErr.Write([]byte(fmt.Sprintf("[line %d] %s\n", s.lineNumber, message)))
+	s.errorAt(s.start, message)
+}
+
+// errorAt reports a problem with the runes consumed since start.
+func (s *Scanner) errorAt(start diagnostic.Position, message string) {
+	s.report(start, diagnostic.CodeLexical, message)
+}
+
+// report records a diagnostic with code for the runes consumed since start.
+func (s *Scanner) report(start diagnostic.Position, code, message string) {
+	span := diagnostic.Span{Start: start, End: s.position}
+	err := diagnostic.NewError(span, code, message)
+	s.errors = append(s.errors, err)
+	s.stdErr.Write([]byte(err.Error() + "\n"))
 }
@@ -6,17 +6,19 @@ import (
 	"testing"
 
 	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestParentheses(t *testing.T) {
 	scanner, stdErr := setupScanner("()\n)(")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenLeftParenthesis, LineNumber: 1},
-		{TokenType: ast.TokenRightParenthesis, LineNumber: 1},
-		{TokenType: ast.TokenRightParenthesis, LineNumber: 2},
-		{TokenType: ast.TokenLeftParenthesis, LineNumber: 2},
+		{TokenType: ast.TokenLeftParenthesis, LineNumber: 1, Lexeme: "("},
+		{TokenType: ast.TokenRightParenthesis, LineNumber: 1, Lexeme: ")"},
+		{TokenType: ast.TokenRightParenthesis, LineNumber: 2, Lexeme: ")"},
+		{TokenType: ast.TokenLeftParenthesis, LineNumber: 2, Lexeme: "("},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -27,10 +29,24 @@ func TestBraces(t *testing.T) {
 	scanner, stdErr := setupScanner("{}\n}{")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenLeftBrace, LineNumber: 1},
-		{TokenType: ast.TokenRightBrace, LineNumber: 1},
-		{TokenType: ast.TokenRightBrace, LineNumber: 2},
-		{TokenType: ast.TokenLeftBrace, LineNumber: 2},
+		{TokenType: ast.TokenLeftBrace, LineNumber: 1, Lexeme: "{"},
+		{TokenType: ast.TokenRightBrace, LineNumber: 1, Lexeme: "}"},
+		{TokenType: ast.TokenRightBrace, LineNumber: 2, Lexeme: "}"},
+		{TokenType: ast.TokenLeftBrace, LineNumber: 2, Lexeme: "{"},
+	}
+	actual := scanner.ScanTokens()
+	compareTokens(t, expected, actual)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestBrackets(t *testing.T) {
+	scanner, stdErr := setupScanner("[]\n][")
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenLeftBracket, LineNumber: 1, Lexeme: "["},
+		{TokenType: ast.TokenRightBracket, LineNumber: 1, Lexeme: "]"},
+		{TokenType: ast.TokenRightBracket, LineNumber: 2, Lexeme: "]"},
+		{TokenType: ast.TokenLeftBracket, LineNumber: 2, Lexeme: "["},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -41,8 +57,8 @@ func TestComma(t *testing.T) {
 	scanner, stdErr := setupScanner(",\n,")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenComma, LineNumber: 1},
-		{TokenType: ast.TokenComma, LineNumber: 2},
+		{TokenType: ast.TokenComma, LineNumber: 1, Lexeme: ","},
+		{TokenType: ast.TokenComma, LineNumber: 2, Lexeme: ","},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -53,8 +69,36 @@ func TestDot(t *testing.T) {
 	scanner, stdErr := setupScanner(".\n.")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenDot, LineNumber: 1},
-		{TokenType: ast.TokenDot, LineNumber: 2},
+		{TokenType: ast.TokenDot, LineNumber: 1, Lexeme: "."},
+		{TokenType: ast.TokenDot, LineNumber: 2, Lexeme: "."},
+	}
+	actual := scanner.ScanTokens()
+	compareTokens(t, expected, actual)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestEllipsis(t *testing.T) {
+	scanner, stdErr := setupScanner("...\n....\n..")
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenEllipsis, LineNumber: 1, Lexeme: "..."},
+		{TokenType: ast.TokenEllipsis, LineNumber: 2, Lexeme: "..."},
+		{TokenType: ast.TokenDot, LineNumber: 2, Lexeme: "."},
+	}
+	actual := scanner.ScanTokens()
+	compareTokens(t, expected, actual)
+	if !strings.Contains(stdErr.String(), `found unexpected ".."`) {
+		t.Fatalf("expected an error for \"..\" while actual is %q", stdErr.String())
+	}
+}
+
+func TestArrow(t *testing.T) {
+	scanner, stdErr := setupScanner("=>\n= >")
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenArrow, LineNumber: 1, Lexeme: "=>"},
+		{TokenType: ast.TokenEqual, LineNumber: 2, Lexeme: "="},
+		{TokenType: ast.TokenGreater, LineNumber: 2, Lexeme: ">"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -65,8 +109,8 @@ func TestPlus(t *testing.T) {
 	scanner, stdErr := setupScanner("+\n+")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenPlus, LineNumber: 1},
-		{TokenType: ast.TokenPlus, LineNumber: 2},
+		{TokenType: ast.TokenPlus, LineNumber: 1, Lexeme: "+"},
+		{TokenType: ast.TokenPlus, LineNumber: 2, Lexeme: "+"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -77,8 +121,8 @@ func TestMinus(t *testing.T) {
 	scanner, stdErr := setupScanner("-\n-")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenMinus, LineNumber: 1},
-		{TokenType: ast.TokenMinus, LineNumber: 2},
+		{TokenType: ast.TokenMinus, LineNumber: 1, Lexeme: "-"},
+		{TokenType: ast.TokenMinus, LineNumber: 2, Lexeme: "-"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -89,8 +133,20 @@ func TestSemicolon(t *testing.T) {
 	scanner, stdErr := setupScanner(";\n;")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenSemicolon, LineNumber: 1},
-		{TokenType: ast.TokenSemicolon, LineNumber: 2},
+		{TokenType: ast.TokenSemicolon, LineNumber: 1, Lexeme: ";"},
+		{TokenType: ast.TokenSemicolon, LineNumber: 2, Lexeme: ";"},
+	}
+	actual := scanner.ScanTokens()
+	compareTokens(t, expected, actual)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestColon(t *testing.T) {
+	scanner, stdErr := setupScanner(":\n:")
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenColon, LineNumber: 1, Lexeme: ":"},
+		{TokenType: ast.TokenColon, LineNumber: 2, Lexeme: ":"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -101,8 +157,8 @@ func TestStar(t *testing.T) {
 	scanner, stdErr := setupScanner("*\n*")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenStar, LineNumber: 1},
-		{TokenType: ast.TokenStar, LineNumber: 2},
+		{TokenType: ast.TokenStar, LineNumber: 1, Lexeme: "*"},
+		{TokenType: ast.TokenStar, LineNumber: 2, Lexeme: "*"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -113,8 +169,8 @@ func TestSlash(t *testing.T) {
 	scanner, stdErr := setupScanner("/\n///\n/")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenSlash, LineNumber: 1},
-		{TokenType: ast.TokenSlash, LineNumber: 3},
+		{TokenType: ast.TokenSlash, LineNumber: 1, Lexeme: "/"},
+		{TokenType: ast.TokenSlash, LineNumber: 3, Lexeme: "/"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -125,9 +181,9 @@ func TestEqual(t *testing.T) {
 	scanner, stdErr := setupScanner("=\n===")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenEqual, LineNumber: 1},
-		{TokenType: ast.TokenEqualEqual, LineNumber: 2},
-		{TokenType: ast.TokenEqual, LineNumber: 2},
+		{TokenType: ast.TokenEqual, LineNumber: 1, Lexeme: "="},
+		{TokenType: ast.TokenEqualEqual, LineNumber: 2, Lexeme: "=="},
+		{TokenType: ast.TokenEqual, LineNumber: 2, Lexeme: "="},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -138,9 +194,9 @@ func TestBang(t *testing.T) {
 	scanner, stdErr := setupScanner("!\n!!=")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenBang, LineNumber: 1},
-		{TokenType: ast.TokenBang, LineNumber: 2},
-		{TokenType: ast.TokenBangEqual, LineNumber: 2},
+		{TokenType: ast.TokenBang, LineNumber: 1, Lexeme: "!"},
+		{TokenType: ast.TokenBang, LineNumber: 2, Lexeme: "!"},
+		{TokenType: ast.TokenBangEqual, LineNumber: 2, Lexeme: "!="},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -151,9 +207,9 @@ func TestGreater(t *testing.T) {
 	scanner, stdErr := setupScanner(">\n>>=")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenGreater, LineNumber: 1},
-		{TokenType: ast.TokenGreater, LineNumber: 2},
-		{TokenType: ast.TokenGreaterEqual, LineNumber: 2},
+		{TokenType: ast.TokenGreater, LineNumber: 1, Lexeme: ">"},
+		{TokenType: ast.TokenGreater, LineNumber: 2, Lexeme: ">"},
+		{TokenType: ast.TokenGreaterEqual, LineNumber: 2, Lexeme: ">="},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -164,9 +220,9 @@ func TestLess(t *testing.T) {
 	scanner, stdErr := setupScanner("<\n<<=")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenLess, LineNumber: 1},
-		{TokenType: ast.TokenLess, LineNumber: 2},
-		{TokenType: ast.TokenLessEqual, LineNumber: 2},
+		{TokenType: ast.TokenLess, LineNumber: 1, Lexeme: "<"},
+		{TokenType: ast.TokenLess, LineNumber: 2, Lexeme: "<"},
+		{TokenType: ast.TokenLessEqual, LineNumber: 2, Lexeme: "<="},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -177,7 +233,7 @@ func TestWhitespaces(t *testing.T) {
 	scanner, stdErr := setupScanner("\r \t\n\n!")
 
 	expected := []ast.Token{
-		{TokenType: ast.TokenBang, LineNumber: 3},
+		{TokenType: ast.TokenBang, LineNumber: 3, Lexeme: "!"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -201,7 +257,7 @@ func TestUnterminatedString(t *testing.T) {
 
 	expected := []ast.Token{
 		{TokenType: ast.TokenString, LineNumber: 1, Lexeme: "\"hello\"", Literal: "hello"},
-		{TokenType: ast.TokenBang, LineNumber: 3},
+		{TokenType: ast.TokenBang, LineNumber: 3, Lexeme: "!"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -210,12 +266,107 @@ func TestUnterminatedString(t *testing.T) {
 	}
 }
 
+func TestStringEscapes(t *testing.T) {
+	scanner, stdErr := setupScanner(`"a\nb\t\"c\"\\\r" "\u{41}\u{1F600}\u{e9}"`)
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenString, LineNumber: 1, Lexeme: `"a\nb\t\"c\"\\\r"`, Literal: "a\nb\t\"c\"\\\r"},
+		{TokenType: ast.TokenString, LineNumber: 1, Lexeme: `"\u{41}\u{1F600}\u{e9}"`, Literal: "A😀é"},
+	}
+	actual := scanner.ScanTokens()
+	compareTokens(t, expected, actual)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestInvalidStringEscapes(t *testing.T) {
+	testcases := map[string]string{
+		`"\q"`:          `invalid escape sequence "\q"`,
+		`"\u41"`:        `expect '{' after "\u"`,
+		`"\u{}"`:        `expect 1 to 6 hexadecimal digits between "\u{" and "}"`,
+		`"\u{1234567}"`: `expect 1 to 6 hexadecimal digits between "\u{" and "}"`,
+		`"\u{41"`:       `expect 1 to 6 hexadecimal digits between "\u{" and "}"`,
+		`"\u{D800}"`:    `invalid unicode code point "D800"`,
+		`"\u{110000}"`:  `invalid unicode code point "110000"`,
+		"\"a\\\n\"":     "unterminated string",
+	}
+	for source, message := range testcases {
+		scanner, stdErr := setupScanner(source)
+		scanner.ScanTokens()
+		if !strings.Contains(stdErr.String(), message) {
+			t.Fatalf("expected %q in stdErr while scanning %s, got %q", message, source, stdErr.String())
+		}
+	}
+}
+
+func TestInvalidStringEscapePosition(t *testing.T) {
+	scanner, _ := setupScanner(`"ab\x"`)
+	scanner.ScanTokens()
+
+	errs := scanner.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	span := errs[0].(diagnostic.Diagnostic).Span
+	if span.Start.Column != 4 || span.End.Column != 6 {
+		t.Fatalf("expected the error to span columns 4 to 6, got %+v", span)
+	}
+}
+
+func TestRawString(t *testing.T) {
+	scanner, stdErr := setupScanner("`a\\n\n\"b\"\r\n`\n!")
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenString, LineNumber: 1, Lexeme: "`a\\n\n\"b\"\r\n`", Literal: "a\\n\n\"b\"\n"},
+		{TokenType: ast.TokenBang, LineNumber: 4, Lexeme: "!"},
+	}
+	actual := scanner.ScanTokens()
+	compareTokens(t, expected, actual)
+	checkStdErrEmpty(t, stdErr)
+}
+
+func TestUnterminatedRawString(t *testing.T) {
+	scanner, stdErr := setupScanner("!\n`hello\n!")
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenBang, LineNumber: 1, Lexeme: "!"},
+	}
+	actual := scanner.ScanTokens()
+	compareTokens(t, expected, actual)
+	if !strings.Contains(stdErr.String(), "unterminated raw string") {
+		t.Fatal("expected unterminated raw string error in stdErr, found nothing")
+	}
+	if errs := scanner.Errors(); len(errs) != 1 || errs[0].(diagnostic.Diagnostic).Code != diagnostic.CodeUnterminatedRawString {
+		t.Fatalf("expected a single %s error, got %v", diagnostic.CodeUnterminatedRawString, errs)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	scanner, stdErr := setupScanner(`"a ${b} c ${ {} } \${d} $" "${"e${f}"}"`)
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenTemplateStart, LineNumber: 1, Lexeme: `"a ${`, Literal: "a "},
+		{TokenType: ast.TokenIdentifier, LineNumber: 1, Lexeme: "b"},
+		{TokenType: ast.TokenTemplateMiddle, LineNumber: 1, Lexeme: "} c ${", Literal: " c "},
+		{TokenType: ast.TokenLeftBrace, LineNumber: 1, Lexeme: "{"},
+		{TokenType: ast.TokenRightBrace, LineNumber: 1, Lexeme: "}"},
+		{TokenType: ast.TokenTemplateEnd, LineNumber: 1, Lexeme: `} \${d} $"`, Literal: " ${d} $"},
+		{TokenType: ast.TokenTemplateStart, LineNumber: 1, Lexeme: `"${`, Literal: ""},
+		{TokenType: ast.TokenTemplateStart, LineNumber: 1, Lexeme: `"e${`, Literal: "e"},
+		{TokenType: ast.TokenIdentifier, LineNumber: 1, Lexeme: "f"},
+		{TokenType: ast.TokenTemplateEnd, LineNumber: 1, Lexeme: `}"`, Literal: ""},
+		{TokenType: ast.TokenTemplateEnd, LineNumber: 1, Lexeme: `}"`, Literal: ""},
+	}
+	actual := scanner.ScanTokens()
+	compareTokens(t, expected, actual)
+	checkStdErrEmpty(t, stdErr)
+}
+
 func TestNumber(t *testing.T) {
 	scanner, stdErr := setupScanner("1234.0\n.0123\n0.1 2")
 
 	expected := []ast.Token{
 		{TokenType: ast.TokenNumber, LineNumber: 1, Lexeme: "1234.0", Literal: float64(1234.0)},
-		{TokenType: ast.TokenDot, LineNumber: 2},
+		{TokenType: ast.TokenDot, LineNumber: 2, Lexeme: "."},
 		{TokenType: ast.TokenNumber, LineNumber: 2, Lexeme: "0123", Literal: float64(123)},
 		{TokenType: ast.TokenNumber, LineNumber: 3, Lexeme: "0.1", Literal: float64(0.1)},
 		{TokenType: ast.TokenNumber, LineNumber: 3, Lexeme: "2", Literal: float64(2)},
@@ -226,7 +377,7 @@ func TestNumber(t *testing.T) {
 }
 
 func TestKeyword(t *testing.T) {
-	scanner, stdErr := setupScanner("misal \njika lain fungsi balikin kosong benar salah selama cetak dan atau")
+	scanner, stdErr := setupScanner("misal \njika lain fungsi balikin kosong benar salah selama cetak dan atau untuk dalam berhenti lanjut kelas ini induk lempar coba tangkap akhirnya")
 	expected := []ast.Token{
 		{TokenType: ast.TokenLet, LineNumber: 1, Lexeme: "misal"},
 		{TokenType: ast.TokenIf, LineNumber: 2, Lexeme: "jika"},
@@ -240,6 +391,17 @@ func TestKeyword(t *testing.T) {
 		{TokenType: ast.TokenPrint, LineNumber: 2, Lexeme: "cetak"},
 		{TokenType: ast.TokenAnd, LineNumber: 2, Lexeme: "dan"},
 		{TokenType: ast.TokenOr, LineNumber: 2, Lexeme: "atau"},
+		{TokenType: ast.TokenFor, LineNumber: 2, Lexeme: "untuk"},
+		{TokenType: ast.TokenIn, LineNumber: 2, Lexeme: "dalam"},
+		{TokenType: ast.TokenBreak, LineNumber: 2, Lexeme: "berhenti"},
+		{TokenType: ast.TokenContinue, LineNumber: 2, Lexeme: "lanjut"},
+		{TokenType: ast.TokenClass, LineNumber: 2, Lexeme: "kelas"},
+		{TokenType: ast.TokenThis, LineNumber: 2, Lexeme: "ini"},
+		{TokenType: ast.TokenSuper, LineNumber: 2, Lexeme: "induk"},
+		{TokenType: ast.TokenThrow, LineNumber: 2, Lexeme: "lempar"},
+		{TokenType: ast.TokenTry, LineNumber: 2, Lexeme: "coba"},
+		{TokenType: ast.TokenCatch, LineNumber: 2, Lexeme: "tangkap"},
+		{TokenType: ast.TokenFinally, LineNumber: 2, Lexeme: "akhirnya"},
 	}
 	actual := scanner.ScanTokens()
 	compareTokens(t, expected, actual)
@@ -248,7 +410,7 @@ func TestKeyword(t *testing.T) {
 
 func TestIdentifier(t *testing.T) {
 	{
-		scanner, stdErr := setupScanner("mimisal \njikaka lalainin fffungsi balikinnn kokokosong benarbenar sasalahlah se lama ce\ntak dandan watau")
+		scanner, stdErr := setupScanner("mimisal \njikaka lalainin fffungsi balikinnn kokokosong benarbenar sasalahlah un se lama ce\ntak dandan watau")
 		expected := []ast.Token{
 			{TokenType: ast.TokenIdentifier, LineNumber: 1, Lexeme: "mimisal"},
 			{TokenType: ast.TokenIdentifier, LineNumber: 2, Lexeme: "jikaka"},
@@ -261,6 +423,7 @@ func TestIdentifier(t *testing.T) {
 			{TokenType: ast.TokenIdentifier, LineNumber: 2, Lexeme: "un"},
 			{TokenType: ast.TokenIdentifier, LineNumber: 2, Lexeme: "se"},
 			{TokenType: ast.TokenIdentifier, LineNumber: 2, Lexeme: "lama"},
+			{TokenType: ast.TokenIdentifier, LineNumber: 2, Lexeme: "ce"},
 			{TokenType: ast.TokenIdentifier, LineNumber: 3, Lexeme: "tak"},
 			{TokenType: ast.TokenIdentifier, LineNumber: 3, Lexeme: "dandan"},
 			{TokenType: ast.TokenIdentifier, LineNumber: 3, Lexeme: "watau"},
@@ -290,7 +453,8 @@ func compareTokens(t *testing.T, expected, actual []ast.Token) {
 	}
 
 	for i, expectedToken := range expected {
-		if !cmp.Equal(expectedToken, actual[i]) {
+		// positions are covered by TestPositions
+		if !cmp.Equal(expectedToken, actual[i], cmpopts.IgnoreFields(ast.Token{}, "Column", "Offset")) {
 			t.Fatalf("expected is %#v while actual is %#v", expectedToken, actual[i])
 		}
 	}
@@ -307,3 +471,26 @@ func setupScanner(testcase string) (*Scanner, *strings.Builder) {
 	w := new(strings.Builder)
 	return NewScanner(r, w), w
 }
+
+func TestPositions(t *testing.T) {
+	scanner, stdErr := setupScanner("misal x = \"é\";\n  cetak x >= 1; // ñ")
+
+	expected := []ast.Token{
+		{TokenType: ast.TokenLet, LineNumber: 1, Column: 1, Offset: 0, Lexeme: "misal"},
+		{TokenType: ast.TokenIdentifier, LineNumber: 1, Column: 7, Offset: 6, Lexeme: "x"},
+		{TokenType: ast.TokenEqual, LineNumber: 1, Column: 9, Offset: 8, Lexeme: "="},
+		{TokenType: ast.TokenString, LineNumber: 1, Column: 11, Offset: 10, Lexeme: "\"é\"", Literal: "é"},
+		{TokenType: ast.TokenSemicolon, LineNumber: 1, Column: 14, Offset: 14, Lexeme: ";"},
+		{TokenType: ast.TokenPrint, LineNumber: 2, Column: 3, Offset: 18, Lexeme: "cetak"},
+		{TokenType: ast.TokenIdentifier, LineNumber: 2, Column: 9, Offset: 24, Lexeme: "x"},
+		{TokenType: ast.TokenGreaterEqual, LineNumber: 2, Column: 11, Offset: 26, Lexeme: ">="},
+		{TokenType: ast.TokenNumber, LineNumber: 2, Column: 14, Offset: 29, Lexeme: "1", Literal: float64(1)},
+		{TokenType: ast.TokenSemicolon, LineNumber: 2, Column: 15, Offset: 30, Lexeme: ";"},
+		{TokenType: ast.TokenEof, LineNumber: 2, Column: 21, Offset: 37},
+	}
+	actual := scanner.ScanTokens()
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Fatalf("unexpected tokens (-expected +actual):\n%s", diff)
+	}
+	checkStdErrEmpty(t, stdErr)
+}
@@ -0,0 +1,445 @@
+package lsp
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
+	"github.com/aselhid/indoscript/internal/format"
+	"github.com/aselhid/indoscript/internal/lexer"
+	"github.com/aselhid/indoscript/internal/resolver"
+)
+
+type symbolKind uint8
+
+const (
+	symbolVariable symbolKind = iota
+	symbolFunction
+	symbolParameter
+	symbolClass
+)
+
+// symbol is a declared name, a misal variable, a fungsi, one of its
+// parameters or a kelas.
+type symbol struct {
+	name       ast.Token
+	kind       symbolKind
+	parameters []ast.Token
+	// defaults holds the default value of every parameter, nil for those
+	// without one
+	defaults []ast.Expr
+	variadic bool
+	// function is the function declaring a parameter
+	function *symbol
+}
+
+// signature describes the symbol the way it is declared.
+func (s *symbol) signature() string {
+	switch s.kind {
+	case symbolFunction:
+		parameters := make([]string, len(s.parameters))
+		for i, parameter := range s.parameters {
+			parameters[i] = parameter.Lexeme
+			if s.defaults[i] != nil {
+				parameters[i] += " = " + format.Expr(s.defaults[i])
+			}
+		}
+		if s.variadic {
+			parameters[len(parameters)-1] = "..." + parameters[len(parameters)-1]
+		}
+		return "fungsi " + s.name.Lexeme + "(" + strings.Join(parameters, ", ") + ")"
+	case symbolParameter:
+		return "parameter " + s.name.Lexeme + " dari " + s.function.signature()
+	case symbolClass:
+		return "kelas " + s.name.Lexeme
+	}
+	return "misal " + s.name.Lexeme
+}
+
+type reference struct {
+	token  ast.Token
+	symbol *symbol
+}
+
+// scope spans the source between the byte offsets start and end, symbols
+// are in declaration order.
+type scope struct {
+	start, end int
+	parent     *scope
+	symbols    []*symbol
+}
+
+// document is an open text document together with what was learned from
+// parsing it. It is rebuilt from scratch on every change.
+type document struct {
+	uri        string
+	text       string
+	lineStarts []int
+
+	diagnostics []Diagnostic
+	scopes      []*scope
+	symbols     []*symbol
+	references  []reference
+	// globals holds the first top level declaration of every name, globals
+	// may be used by functions declared before them
+	globals map[string]*symbol
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, text: text, lineStarts: []int{0}, globals: map[string]*symbol{}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+
+	scanner := lexer.NewScanner(strings.NewReader(text), io.Discard)
+	parser := ast.NewParser(scanner.ScanTokens(), io.Discard)
+	stmts, _ := parser.Parse()
+	resolver := resolver.NewResolver(io.Discard)
+	resolver.Resolve(stmts)
+	for _, errs := range [][]error{scanner.Errors(), parser.Errors(), resolver.Errors()} {
+		for _, err := range errs {
+			d.diagnostics = append(d.diagnostics, d.diagnostic(err))
+		}
+	}
+
+	global := &scope{start: 0, end: math.MaxInt}
+	d.scopes = append(d.scopes, global)
+	for _, stmt := range stmts {
+		var name ast.Token
+		switch s := stmt.(type) {
+		case ast.VarStmt:
+			name = s.Identifier
+		case ast.FuncStmt:
+			name = s.Name
+		case ast.ClassStmt:
+			name = s.Name
+		default:
+			continue
+		}
+		if _, ok := d.globals[name.Lexeme]; !ok {
+			d.globals[name.Lexeme] = nil
+		}
+	}
+	(&analyzer{document: d, scope: global}).stmts(stmts)
+	return d
+}
+
+func (d *document) diagnostic(err error) Diagnostic {
+	var diag diagnostic.Diagnostic
+	if !errors.As(err, &diag) {
+		return Diagnostic{Severity: SeverityError, Source: "indoscript", Message: err.Error()}
+	}
+	severity := SeverityError
+	if diag.Severity == diagnostic.SeverityWarning {
+		severity = SeverityWarning
+	}
+	return Diagnostic{
+		Range:    Range{Start: d.position(diag.Span.Start.Offset), End: d.position(diag.Span.End.Offset)},
+		Severity: severity,
+		Code:     diag.Code,
+		Source:   "indoscript",
+		Message:  diag.Message,
+	}
+}
+
+// position converts a byte offset to a protocol position.
+func (d *document) position(offset int) Position {
+	offset = min(max(offset, 0), len(d.text))
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	character := 0
+	for _, r := range d.text[d.lineStarts[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// offset converts a protocol position to a byte offset, positions past the
+// end of a line are clamped to it.
+func (d *document) offset(position Position) int {
+	if position.Line < 0 {
+		return 0
+	}
+	if position.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	offset := d.lineStarts[position.Line]
+	for character := 0; character < position.Character && offset < len(d.text); {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		if r == '\n' {
+			break
+		}
+		character += utf16Len(r)
+		offset += size
+	}
+	return offset
+}
+
+func (d *document) tokenRange(token ast.Token) Range {
+	return Range{Start: d.position(token.Offset), End: d.position(token.Offset + len(token.Lexeme))}
+}
+
+// symbolAt returns the symbol declared or referenced by the identifier
+// touching offset, together with that identifier.
+func (d *document) symbolAt(offset int) (*symbol, ast.Token, bool) {
+	touches := func(token ast.Token) bool {
+		return token.Offset <= offset && offset <= token.Offset+len(token.Lexeme)
+	}
+	for _, reference := range d.references {
+		if touches(reference.token) {
+			return reference.symbol, reference.token, true
+		}
+	}
+	for _, symbol := range d.symbols {
+		if touches(symbol.name) {
+			return symbol, symbol.name, true
+		}
+	}
+	return nil, ast.Token{}, false
+}
+
+// visible returns the symbols that can be used at offset, an inner
+// declaration hides an outer one with the same name.
+func (d *document) visible(offset int) []*symbol {
+	innermost := d.scopes[0]
+	for _, scope := range d.scopes[1:] {
+		if scope.start < offset && offset <= scope.end && scope.start >= innermost.start {
+			innermost = scope
+		}
+	}
+
+	var symbols []*symbol
+	seen := map[string]bool{}
+	for scope := innermost; scope != nil; scope = scope.parent {
+		for i := len(scope.symbols) - 1; i >= 0; i-- {
+			symbol := scope.symbols[i]
+			if seen[symbol.name.Lexeme] || (scope.parent != nil && symbol.name.Offset >= offset) {
+				continue
+			}
+			seen[symbol.name.Lexeme] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].name.Lexeme < symbols[j].name.Lexeme })
+	return symbols
+}
+
+// analyzer walks the program the way the resolver does and records every
+// declaration and the declaration each identifier refers to.
+type analyzer struct {
+	document *document
+	scope    *scope
+}
+
+func (a *analyzer) declare(symbol *symbol) {
+	a.scope.symbols = append(a.scope.symbols, symbol)
+	a.document.symbols = append(a.document.symbols, symbol)
+	if a.scope.parent == nil && a.document.globals[symbol.name.Lexeme] == nil {
+		a.document.globals[symbol.name.Lexeme] = symbol
+	}
+}
+
+func (a *analyzer) refer(token ast.Token) {
+	for scope := a.scope; scope != nil; scope = scope.parent {
+		for i := len(scope.symbols) - 1; i >= 0; i-- {
+			if scope.symbols[i].name.Lexeme == token.Lexeme {
+				a.document.references = append(a.document.references, reference{token, scope.symbols[i]})
+				return
+			}
+		}
+	}
+	// a global declared further down, bound once the walk reaches it
+	if _, ok := a.document.globals[token.Lexeme]; ok {
+		a.document.references = append(a.document.references, reference{token: token})
+	}
+}
+
+func (a *analyzer) beginScope(start, end int) {
+	a.scope = &scope{start: start, end: end, parent: a.scope}
+	a.document.scopes = append(a.document.scopes, a.scope)
+}
+
+func (a *analyzer) endScope() {
+	a.scope = a.scope.parent
+}
+
+func (a *analyzer) stmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		a.stmt(stmt)
+	}
+	if a.scope.parent == nil {
+		// bind the forward references to globals now that all are declared
+		for i, reference := range a.document.references {
+			if reference.symbol == nil {
+				a.document.references[i].symbol = a.document.globals[reference.token.Lexeme]
+			}
+		}
+	}
+}
+
+func (a *analyzer) block(block ast.BlockStmt) {
+	a.beginScope(block.LeftBrace.Offset, block.RightBrace.Offset)
+	a.stmts(block.Statements)
+	a.endScope()
+}
+
+func (a *analyzer) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case ast.PrintStmt:
+		a.expr(s.Expression)
+	case ast.ExprStmt:
+		a.expr(s.Expression)
+	case ast.VarStmt:
+		a.expr(s.Expression)
+		a.declare(&symbol{name: s.Identifier, kind: symbolVariable})
+	case ast.AssignStmt:
+		a.expr(s.Expression)
+		a.refer(s.Identifier)
+	case ast.IndexAssignStmt:
+		a.expr(s.Object)
+		a.expr(s.Index)
+		a.expr(s.Expression)
+	case ast.PropertyAssignStmt:
+		a.expr(s.Object)
+		a.expr(s.Expression)
+	case ast.BlockStmt:
+		a.block(s)
+	case ast.IfStmt:
+		a.expr(s.Condition)
+		a.block(s.ThenStmt)
+		if s.HasElse() {
+			a.stmt(s.ElseStmt)
+		}
+	case ast.WhileStmt:
+		a.expr(s.Condition)
+		a.block(s.Stmt)
+	case ast.ForStmt:
+		a.beginScope(s.Keyword.Offset, s.Body.RightBrace.Offset)
+		if s.Initializer != nil {
+			a.stmt(s.Initializer)
+		}
+		if s.Condition != nil {
+			a.expr(s.Condition)
+		}
+		if s.Increment != nil {
+			a.stmt(s.Increment)
+		}
+		a.block(s.Body)
+		a.endScope()
+	case ast.ForInStmt:
+		a.expr(s.Iterable)
+		a.beginScope(s.Keyword.Offset, s.Body.RightBrace.Offset)
+		a.declare(&symbol{name: s.Variable, kind: symbolVariable})
+		a.block(s.Body)
+		a.endScope()
+	case ast.FuncStmt:
+		function := &symbol{name: s.Name, kind: symbolFunction, parameters: s.Parameters, defaults: s.Defaults, variadic: s.Variadic}
+		a.declare(function)
+		a.function(s, function, s.Name.Offset, s.RightBrace.Offset)
+	case ast.ClassStmt:
+		if s.Superclass != nil {
+			a.refer(s.Superclass.Identifier)
+		}
+		a.declare(&symbol{name: s.Name, kind: symbolClass})
+		for _, method := range s.Methods {
+			// methods are properties of the objek, not names in the scope
+			function := &symbol{name: method.Name, kind: symbolFunction, parameters: method.Parameters, defaults: method.Defaults, variadic: method.Variadic}
+			a.function(method, function, method.Name.Offset, method.RightBrace.Offset)
+		}
+	case ast.ReturnStmt:
+		if s.Value != nil {
+			a.expr(s.Value)
+		}
+	case ast.ThrowStmt:
+		a.expr(s.Value)
+	case ast.TryStmt:
+		a.block(s.Body)
+		if s.Catch != nil {
+			a.beginScope(s.CatchName.Offset, s.Catch.RightBrace.Offset)
+			a.declare(&symbol{name: s.CatchName, kind: symbolVariable})
+			a.stmts(s.Catch.Statements)
+			a.endScope()
+		}
+		if s.Finally != nil {
+			a.block(*s.Finally)
+		}
+	}
+}
+
+// function walks the parameters and body of a fungsi in a scope between the
+// byte offsets start and end.
+func (a *analyzer) function(declaration ast.FuncStmt, function *symbol, start, end int) {
+	a.beginScope(start, end)
+	for i, parameter := range declaration.Parameters {
+		if declaration.Defaults[i] != nil {
+			a.expr(declaration.Defaults[i])
+		}
+		a.declare(&symbol{name: parameter, kind: symbolParameter, function: function})
+	}
+	a.stmts(declaration.Body)
+	a.endScope()
+}
+
+func (a *analyzer) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case ast.BinaryExpr:
+		a.expr(e.Left)
+		a.expr(e.Right)
+	case ast.LogicalExpr:
+		a.expr(e.Left)
+		a.expr(e.Right)
+	case ast.UnaryExpr:
+		a.expr(e.Right)
+	case ast.GroupExpr:
+		a.expr(e.Expression)
+	case ast.VarExpr:
+		a.refer(e.Identifier)
+	case ast.CallExpr:
+		a.expr(e.Callee)
+		for _, argument := range e.Arguments {
+			a.expr(argument)
+		}
+	case ast.ListExpr:
+		for _, element := range e.Elements {
+			a.expr(element)
+		}
+	case ast.IndexExpr:
+		a.expr(e.Object)
+		a.expr(e.Index)
+	case ast.PropertyExpr:
+		a.expr(e.Object)
+	case ast.TemplateExpr:
+		for _, value := range e.Values {
+			a.expr(value)
+		}
+	case ast.MapExpr:
+		for i := range e.Keys {
+			a.expr(e.Keys[i])
+			a.expr(e.Values[i])
+		}
+	case ast.FuncExpr:
+		declaration := e.Declaration
+		function := &symbol{kind: symbolFunction, parameters: declaration.Parameters, defaults: declaration.Defaults, variadic: declaration.Variadic}
+		end := declaration.RightBrace.Offset
+		if e.Arrow {
+			// the body ends with the last token of the expression
+			end += len(declaration.RightBrace.Lexeme)
+		}
+		a.function(declaration, function, declaration.Keyword.Offset, end)
+	}
+}
+
+// utf16Len returns the number of UTF-16 code units encoding r, protocol
+// positions count those.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
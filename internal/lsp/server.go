@@ -0,0 +1,232 @@
+// Package lsp implements a Language Server Protocol server for indoscript
+// over stdio. It publishes diagnostics for every open document and answers
+// go to definition, hover and completion requests.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/aselhid/indoscript/internal/lexer"
+)
+
+// ErrExitWithoutShutdown is returned by Serve when the client sends exit
+// before shutdown, the server process should then exit with status 1.
+var ErrExitWithoutShutdown = errors.New("exit notification received before shutdown")
+
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	shutdown bool
+
+	documents map[string]*document
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends exit or in is exhausted.
+func (s *Server) Serve() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err != nil {
+			var rpcErr *responseError
+			if errors.As(err, &rpcErr) {
+				if err := s.reply(nil, nil, rpcErr); err != nil {
+					return err
+				}
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg message) error {
+	if msg.ID == nil {
+		return s.notification(msg)
+	}
+	if s.shutdown {
+		return s.reply(msg.ID, nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"})
+	}
+
+	result, err := s.request(msg)
+	var rpcErr *responseError
+	if err != nil && !errors.As(err, &rpcErr) {
+		rpcErr = &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return s.reply(msg.ID, result, rpcErr)
+}
+
+func (s *Server) request(msg message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:   1,
+				DefinitionProvider: true,
+				HoverProvider:      true,
+				CompletionProvider: CompletionOptions{},
+			},
+			ServerInfo: ServerInfo{Name: "indoscript"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+// notification handles a message that expects no reply, unknown ones are
+// ignored as the protocol requires.
+func (s *Server) notification(msg message) error {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	}
+	return nil
+}
+
+// update reanalyzes a document and publishes its diagnostics.
+func (s *Server) update(uri, text string) error {
+	document := newDocument(uri, text)
+	s.documents[uri] = document
+	diagnostics := document.diagnostics
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+func (s *Server) definition(params TextDocumentPositionParams) *Location {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	symbol, _, ok := document.symbolAt(document.offset(params.Position))
+	if !ok || symbol == nil {
+		return nil
+	}
+	return &Location{URI: document.uri, Range: document.tokenRange(symbol.name)}
+}
+
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	symbol, token, ok := document.symbolAt(document.offset(params.Position))
+	if !ok || symbol == nil {
+		return nil
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```indoscript\n" + symbol.signature() + "\n```"},
+		Range:    document.tokenRange(token),
+	}
+}
+
+func (s *Server) completion(params TextDocumentPositionParams) []CompletionItem {
+	items := []CompletionItem{}
+	for _, keyword := range lexer.Keywords() {
+		items = append(items, CompletionItem{Label: keyword, Kind: CompletionKeyword})
+	}
+	document, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return items
+	}
+	for _, symbol := range document.visible(document.offset(params.Position)) {
+		kind := CompletionVariable
+		switch symbol.kind {
+		case symbolFunction:
+			kind = CompletionFunction
+		case symbolClass:
+			kind = CompletionClass
+		}
+		items = append(items, CompletionItem{Label: symbol.name.Lexeme, Kind: kind, Detail: symbol.signature()})
+	}
+	return items
+}
+
+func (s *Server) reply(id *json.RawMessage, result any, rpcErr *responseError) error {
+	if id == nil {
+		null := json.RawMessage("null")
+		id = &null
+	}
+	msg := message{ID: id}
+	if rpcErr != nil {
+		msg.Error = rpcErr
+		return writeMessage(s.out, msg)
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	msg.Result = body
+	return writeMessage(s.out, msg)
+}
+
+func (s *Server) notify(method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, message{Method: method, Params: body})
+}
@@ -0,0 +1,326 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// client drives a Server over pipes the way an editor does.
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, in: clientOut, out: bufio.NewReader(clientIn), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *client) send(msg message) {
+	c.t.Helper()
+	if err := writeMessage(c.in, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	body, _ := json.Marshal(params)
+	c.send(message{Method: method, Params: body})
+}
+
+// call sends a request and decodes the result of its response into result.
+func (c *client) call(method string, params any, result any) *responseError {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	body, _ := json.Marshal(params)
+	c.send(message{ID: &id, Method: method, Params: body})
+
+	msg := c.receive()
+	if msg.ID == nil || string(*msg.ID) != string(id) {
+		c.t.Fatalf("expected a response to %s while actual is %+v", method, msg)
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return nil
+}
+
+func (c *client) receive() message {
+	c.t.Helper()
+	msg, err := readMessage(c.out)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+	msg := c.receive()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics while actual is %+v", msg)
+	}
+	var params PublishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		c.t.Fatal(err)
+	}
+	return params
+}
+
+const uri = "file:///skrip.indos"
+
+const source = `misal nama = "dunia";
+fungsi sapa(salam, tujuan) {
+    misal kalimat = salam + tujuan;
+    cetak kalimat;
+}
+sapa("halo ", nama);
+`
+
+func open(c *client, text string) PublishDiagnosticsParams {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, Version: 1, Text: text},
+	})
+	return c.diagnostics()
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	c := newClient(t)
+	var result InitializeResult
+	if err := c.call("initialize", map[string]any{}, &result); err != nil {
+		t.Fatal(err)
+	}
+	capabilities := result.Capabilities
+	if capabilities.TextDocumentSync != 1 || !capabilities.DefinitionProvider || !capabilities.HoverProvider {
+		t.Fatalf("unexpected capabilities %+v", capabilities)
+	}
+	c.notify("initialized", map[string]any{})
+
+	if err := c.call("workspace/symbol", map[string]any{}, nil); err == nil || err.Code != codeMethodNotFound {
+		t.Fatalf("expected method not found while actual is %v", err)
+	}
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.call("textDocument/hover", at(0, 0), nil); err == nil || err.Code != codeInvalidRequest {
+		t.Fatalf("expected invalid request after shutdown while actual is %v", err)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Fatalf("expected a clean exit while actual is %v", err)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	c := newClient(t)
+	c.notify("exit", nil)
+	if err := <-c.done; err != ErrExitWithoutShutdown {
+		t.Fatalf("expected %v while actual is %v", ErrExitWithoutShutdown, err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	if params := open(c, source); len(params.Diagnostics) != 0 || params.URI != uri {
+		t.Fatalf("expected no diagnostics while actual is %+v", params)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: uri},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "misal a = ;\ncetak \"tutup\nmisal b = 1;\n"}},
+	})
+	expected := []Diagnostic{
+		{Range: Range{Position{0, 10}, Position{0, 11}}, Severity: SeverityError, Code: "syntax", Source: "indoscript", Message: "expect expression"},
+		{Range: Range{Position{1, 6}, Position{1, 12}}, Severity: SeverityError, Code: "lexical", Source: "indoscript", Message: "unterminated string"},
+	}
+	params := c.diagnostics()
+	if len(params.Diagnostics) < len(expected) {
+		t.Fatalf("expected at least %d diagnostics while actual is %+v", len(expected), params.Diagnostics)
+	}
+	for _, want := range expected {
+		found := false
+		for _, actual := range params.Diagnostics {
+			found = found || actual == want
+		}
+		if !found {
+			t.Errorf("expected diagnostic %+v in %+v", want, params.Diagnostics)
+		}
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	if params := c.diagnostics(); len(params.Diagnostics) != 0 {
+		t.Fatalf("expected closing to clear diagnostics while actual is %+v", params)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c := newClient(t)
+	open(c, source)
+
+	testcases := map[string]struct {
+		position TextDocumentPositionParams
+		expected *Range
+	}{
+		"global variable": {at(5, 15), &Range{Position{0, 6}, Position{0, 10}}},
+		"function":        {at(5, 1), &Range{Position{1, 7}, Position{1, 11}}},
+		"parameter":       {at(2, 20), &Range{Position{1, 12}, Position{1, 17}}},
+		"local variable":  {at(3, 12), &Range{Position{2, 10}, Position{2, 17}}},
+		"declaration":     {at(0, 7), &Range{Position{0, 6}, Position{0, 10}}},
+		"not a name":      {at(3, 4), nil},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			var location *Location
+			if err := c.call("textDocument/definition", tc.position, &location); err != nil {
+				t.Fatal(err)
+			}
+			if tc.expected == nil {
+				if location != nil {
+					t.Fatalf("expected no location while actual is %+v", location)
+				}
+				return
+			}
+			if location == nil || location.URI != uri || location.Range != *tc.expected {
+				t.Fatalf("expected %+v while actual is %+v", tc.expected, location)
+			}
+		})
+	}
+}
+
+func TestDefinitionOfLaterGlobal(t *testing.T) {
+	c := newClient(t)
+	open(c, "fungsi f() {\n    balikin g();\n}\nfungsi g() {\n    balikin 1;\n}\n")
+
+	var location *Location
+	if err := c.call("textDocument/definition", at(1, 13), &location); err != nil {
+		t.Fatal(err)
+	}
+	if location == nil || location.Range.Start != (Position{3, 7}) {
+		t.Fatalf("expected the declaration of g while actual is %+v", location)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	open(c, source)
+
+	var hover *Hover
+	if err := c.call("textDocument/hover", at(5, 2), &hover); err != nil {
+		t.Fatal(err)
+	}
+	expected := "```indoscript\nfungsi sapa(salam, tujuan)\n```"
+	if hover == nil || hover.Contents.Value != expected || hover.Range != (Range{Position{5, 0}, Position{5, 4}}) {
+		t.Fatalf("unexpected hover %+v", hover)
+	}
+
+	if err := c.call("textDocument/hover", at(2, 22), &hover); err != nil {
+		t.Fatal(err)
+	}
+	if hover == nil || !strings.Contains(hover.Contents.Value, "parameter salam dari fungsi sapa(salam, tujuan)") {
+		t.Fatalf("unexpected hover %+v", hover)
+	}
+}
+
+func TestHoverDefaults(t *testing.T) {
+	c := newClient(t)
+	open(c, "fungsi f(a, b = [1, a + 2], ...c) {}\n")
+
+	var hover *Hover
+	if err := c.call("textDocument/hover", at(0, 7), &hover); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "```indoscript\nfungsi f(a, b = [1, a + 2], ...c)\n```"; hover == nil || hover.Contents.Value != expected {
+		t.Fatalf("unexpected hover %+v", hover)
+	}
+}
+
+func TestAnonymousFunction(t *testing.T) {
+	c := newClient(t)
+	open(c, "misal kali = 2;\nmisal f = fungsi (x, y = kali) => x * y;\n")
+
+	var location *Location
+	if err := c.call("textDocument/definition", at(1, 34), &location); err != nil {
+		t.Fatal(err)
+	}
+	if location == nil || location.Range != (Range{Position{1, 18}, Position{1, 19}}) {
+		t.Fatalf("expected the parameter x while actual is %+v", location)
+	}
+
+	var hover *Hover
+	if err := c.call("textDocument/hover", at(1, 38), &hover); err != nil {
+		t.Fatal(err)
+	}
+	if hover == nil || !strings.Contains(hover.Contents.Value, "parameter y dari fungsi (x, y = kali)") {
+		t.Fatalf("unexpected hover %+v", hover)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	open(c, source)
+
+	labels := func(position TextDocumentPositionParams) map[string]int {
+		var items []CompletionItem
+		if err := c.call("textDocument/completion", position, &items); err != nil {
+			t.Fatal(err)
+		}
+		kinds := map[string]int{}
+		for _, item := range items {
+			kinds[item.Label] = item.Kind
+		}
+		return kinds
+	}
+
+	inside := labels(at(3, 4))
+	for label, kind := range map[string]int{
+		"misal":   CompletionKeyword,
+		"selama":  CompletionKeyword,
+		"nama":    CompletionVariable,
+		"sapa":    CompletionFunction,
+		"salam":   CompletionVariable,
+		"kalimat": CompletionVariable,
+	} {
+		if inside[label] != kind {
+			t.Errorf("expected %s of kind %d inside the function while actual is %d", label, kind, inside[label])
+		}
+	}
+
+	outside := labels(at(5, 0))
+	for _, label := range []string{"salam", "tujuan", "kalimat"} {
+		if _, ok := outside[label]; ok {
+			t.Errorf("expected %s not to be offered outside the function", label)
+		}
+	}
+	if outside["nama"] != CompletionVariable {
+		t.Errorf("expected nama to be offered outside the function")
+	}
+}
@@ -0,0 +1,400 @@
+package resolver
+
+import (
+	"io"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
+)
+
+type functionType uint8
+
+const (
+	functionNone functionType = iota
+	functionFunction
+	functionMethod
+	functionInitializer
+)
+
+type classType uint8
+
+const (
+	classNone classType = iota
+	classClass
+	classSubclass
+)
+
+// Resolver walks the parsed program once before it is interpreted and binds
+// every variable reference to the scope that declares it, so closures see the
+// variables of the scope they were defined in rather than the one they are
+// called from.
+type Resolver struct {
+	stdErr          io.Writer
+	scopes          []map[string]bool
+	currentFunction functionType
+	currentClass    classType
+	hasError        bool
+	errors          []error
+}
+
+func NewResolver(stdErr io.Writer) *Resolver {
+	return &Resolver{
+		stdErr: stdErr,
+	}
+}
+
+func (r *Resolver) Resolve(stmts []ast.Stmt) bool {
+	r.resolveStmts(stmts)
+	return r.hasError
+}
+
+// Errors returns every error reported while resolving.
+func (r *Resolver) Errors() []error {
+	return r.errors
+}
+
+func (r *Resolver) VisitPrintStmt(stmt ast.PrintStmt) ast.Completion {
+	r.resolveExpr(stmt.Expression)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitExprStmt(stmt ast.ExprStmt) ast.Completion {
+	r.resolveExpr(stmt.Expression)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitVarStmt(stmt ast.VarStmt) ast.Completion {
+	r.declare(stmt.Identifier)
+	r.resolveExpr(stmt.Expression)
+	r.define(stmt.Identifier)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitAssignStmt(stmt ast.AssignStmt) ast.Completion {
+	r.resolveExpr(stmt.Expression)
+	r.resolveLocal(stmt.Identifier, stmt.Binding)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitPropertyAssignStmt(stmt ast.PropertyAssignStmt) ast.Completion {
+	r.resolveExpr(stmt.Object)
+	r.resolveExpr(stmt.Expression)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitIndexAssignStmt(stmt ast.IndexAssignStmt) ast.Completion {
+	r.resolveExpr(stmt.Object)
+	r.resolveExpr(stmt.Index)
+	r.resolveExpr(stmt.Expression)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitBlockStmt(stmt ast.BlockStmt) ast.Completion {
+	r.beginScope()
+	r.resolveStmts(stmt.Statements)
+	r.endScope()
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitIfStmt(stmt ast.IfStmt) ast.Completion {
+	r.resolveExpr(stmt.Condition)
+	r.VisitBlockStmt(stmt.ThenStmt)
+	if stmt.HasElse() {
+		stmt.ElseStmt.Accept(r)
+	}
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitWhileStmt(stmt ast.WhileStmt) ast.Completion {
+	r.resolveExpr(stmt.Condition)
+	r.VisitBlockStmt(stmt.Stmt)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitForStmt(stmt ast.ForStmt) ast.Completion {
+	r.beginScope()
+	if stmt.Initializer != nil {
+		r.resolveStmts([]ast.Stmt{stmt.Initializer})
+	}
+	if stmt.Condition != nil {
+		r.resolveExpr(stmt.Condition)
+	}
+	r.VisitBlockStmt(stmt.Body)
+	if stmt.Increment != nil {
+		r.resolveStmts([]ast.Stmt{stmt.Increment})
+	}
+	r.endScope()
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitForInStmt(stmt ast.ForInStmt) ast.Completion {
+	r.resolveExpr(stmt.Iterable)
+	r.beginScope()
+	r.declare(stmt.Variable)
+	r.define(stmt.Variable)
+	r.VisitBlockStmt(stmt.Body)
+	r.endScope()
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitFuncStmt(stmt ast.FuncStmt) ast.Completion {
+	// defined before the body is resolved so the function can call itself
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.resolveFunction(stmt, functionFunction)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt) ast.Completion {
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	enclosingClass := r.currentClass
+	r.currentClass = classClass
+	if stmt.Superclass != nil {
+		if stmt.Superclass.Identifier.Lexeme == stmt.Name.Lexeme {
+			r.error(stmt.Superclass.Identifier, "a kelas can't inherit from itself")
+		}
+		r.currentClass = classSubclass
+		r.resolveExpr(*stmt.Superclass)
+		// induk is declared around the scope holding ini
+		r.beginScope()
+		r.scopes[len(r.scopes)-1]["induk"] = true
+	}
+	// methods are closures over a scope holding ini, which calling a method
+	// binds to the objek
+	r.beginScope()
+	r.scopes[len(r.scopes)-1]["ini"] = true
+	for _, method := range stmt.Methods {
+		kind := functionMethod
+		if method.Name.Lexeme == ast.InitializerName {
+			kind = functionInitializer
+		}
+		r.resolveFunction(method, kind)
+	}
+	r.endScope()
+	if stmt.Superclass != nil {
+		r.endScope()
+	}
+	r.currentClass = enclosingClass
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitReturnStmt(stmt ast.ReturnStmt) ast.Completion {
+	if r.currentFunction == functionNone {
+		r.error(stmt.Keyword, "can't use balikin outside of a fungsi")
+	}
+	if stmt.Value != nil && r.currentFunction == functionInitializer {
+		r.error(stmt.Keyword, "can't balikin a value from inisialisasi")
+	}
+	if stmt.Value != nil {
+		r.resolveExpr(stmt.Value)
+	}
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitBreakStmt(stmt ast.BreakStmt) ast.Completion {
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitContinueStmt(stmt ast.ContinueStmt) ast.Completion {
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitThrowStmt(stmt ast.ThrowStmt) ast.Completion {
+	r.resolveExpr(stmt.Value)
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitTryStmt(stmt ast.TryStmt) ast.Completion {
+	r.VisitBlockStmt(stmt.Body)
+	if stmt.Catch != nil {
+		// the galat shares the scope of the tangkap block
+		r.beginScope()
+		r.declare(stmt.CatchName)
+		r.define(stmt.CatchName)
+		r.resolveStmts(stmt.Catch.Statements)
+		r.endScope()
+	}
+	if stmt.Finally != nil {
+		r.VisitBlockStmt(*stmt.Finally)
+	}
+	return ast.Completion{}
+}
+
+func (r *Resolver) VisitBinaryExpr(expr ast.BinaryExpr) (any, error) {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) VisitLogicalExpr(expr ast.LogicalExpr) (any, error) {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) VisitUnaryExpr(expr ast.UnaryExpr) (any, error) {
+	r.resolveExpr(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) VisitPrimaryExpr(expr ast.PrimaryExpr) (any, error) {
+	return nil, nil
+}
+
+func (r *Resolver) VisitGroupExpr(expr ast.GroupExpr) (any, error) {
+	r.resolveExpr(expr.Expression)
+	return nil, nil
+}
+
+func (r *Resolver) VisitVarExpr(expr ast.VarExpr) (any, error) {
+	if len(r.scopes) > 0 {
+		if defined, ok := r.scopes[len(r.scopes)-1][expr.Identifier.Lexeme]; ok && !defined {
+			r.error(expr.Identifier, "can't read local variable in its own initializer")
+		}
+	}
+	r.resolveLocal(expr.Identifier, expr.Binding)
+	return nil, nil
+}
+
+func (r *Resolver) VisitCallExpr(expr ast.CallExpr) (any, error) {
+	r.resolveExpr(expr.Callee)
+	for _, argument := range expr.Arguments {
+		r.resolveExpr(argument)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitListExpr(expr ast.ListExpr) (any, error) {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitIndexExpr(expr ast.IndexExpr) (any, error) {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil, nil
+}
+
+func (r *Resolver) VisitMapExpr(expr ast.MapExpr) (any, error) {
+	for i := range expr.Keys {
+		r.resolveExpr(expr.Keys[i])
+		r.resolveExpr(expr.Values[i])
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitFuncExpr(expr ast.FuncExpr) (any, error) {
+	r.resolveFunction(expr.Declaration, functionFunction)
+	return nil, nil
+}
+
+func (r *Resolver) VisitPropertyExpr(expr ast.PropertyExpr) (any, error) {
+	r.resolveExpr(expr.Object)
+	return nil, nil
+}
+
+func (r *Resolver) VisitThisExpr(expr ast.ThisExpr) (any, error) {
+	if r.currentClass == classNone {
+		r.error(expr.Keyword, "can't use ini outside of a kelas")
+		return nil, nil
+	}
+	r.resolveLocal(expr.Keyword, expr.Binding)
+	return nil, nil
+}
+
+func (r *Resolver) VisitSuperExpr(expr ast.SuperExpr) (any, error) {
+	switch r.currentClass {
+	case classNone:
+		r.error(expr.Keyword, "can't use induk outside of a kelas")
+		return nil, nil
+	case classClass:
+		r.error(expr.Keyword, "can't use induk in a kelas without an induk kelas")
+		return nil, nil
+	}
+	r.resolveLocal(expr.Keyword, expr.Binding)
+	return nil, nil
+}
+
+func (r *Resolver) VisitTemplateExpr(expr ast.TemplateExpr) (any, error) {
+	for _, value := range expr.Values {
+		r.resolveExpr(value)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) resolveStmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		stmt.Accept(r)
+	}
+}
+
+func (r *Resolver) resolveExpr(expr ast.Expr) {
+	expr.Accept(r)
+}
+
+func (r *Resolver) resolveFunction(stmt ast.FuncStmt, kind functionType) {
+	enclosingFunction := r.currentFunction
+	r.currentFunction = kind
+
+	r.beginScope()
+	for i, parameter := range stmt.Parameters {
+		// a default value only sees the parameters before it
+		if stmt.Defaults[i] != nil {
+			r.resolveExpr(stmt.Defaults[i])
+		}
+		r.declare(parameter)
+		r.define(parameter)
+	}
+	r.resolveStmts(stmt.Body)
+	r.endScope()
+
+	r.currentFunction = enclosingFunction
+}
+
+func (r *Resolver) resolveLocal(identifier ast.Token, binding *ast.Binding) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, ok := r.scopes[i][identifier.Lexeme]; ok {
+			binding.Depth = len(r.scopes) - 1 - i
+			return
+		}
+	}
+	// not found in any local scope, assume it is global
+	binding.Depth = -1
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]bool))
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *Resolver) declare(identifier ast.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[identifier.Lexeme]; ok {
+		r.error(identifier, "variable with this name is already declared in this scope")
+	}
+	scope[identifier.Lexeme] = false
+}
+
+func (r *Resolver) define(identifier ast.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][identifier.Lexeme] = true
+}
+
+func (r *Resolver) error(token ast.Token, message string) {
+	r.hasError = true
+	err := diagnostic.NewError(token.Span(), diagnostic.CodeResolve, message)
+	r.errors = append(r.errors, err)
+	r.stdErr.Write([]byte(err.Error() + "\n"))
+}
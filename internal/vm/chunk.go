@@ -0,0 +1,108 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aselhid/indoscript/internal/ast"
+)
+
+// Chunk is a compiled sequence of instructions together with the constants
+// they refer to.
+type Chunk struct {
+	Code      []byte
+	Constants []any
+	// positions maps ranges of Code back to the token they were compiled
+	// from, a new entry is only added when the token changes.
+	positions []position
+}
+
+type position struct {
+	offset int
+	token  ast.Token
+}
+
+func (c *Chunk) write(b byte, token ast.Token) {
+	if n := len(c.positions); n == 0 || c.positions[n-1].token != token {
+		c.positions = append(c.positions, position{offset: len(c.Code), token: token})
+	}
+	c.Code = append(c.Code, b)
+}
+
+// TokenAt returns the token the instruction at offset was compiled from.
+func (c *Chunk) TokenAt(offset int) ast.Token {
+	i := sort.Search(len(c.positions), func(i int) bool {
+		return c.positions[i].offset > offset
+	})
+	if i == 0 {
+		return ast.Token{}
+	}
+	return c.positions[i-1].token
+}
+
+// Disassemble writes a human readable listing of the chunk to w.
+func (c *Chunk) Disassemble(w io.Writer, name string) {
+	fmt.Fprintf(w, "== %s ==\n", name)
+	for offset := 0; offset < len(c.Code); {
+		offset = c.disassembleInstruction(w, offset)
+	}
+	for _, constant := range c.Constants {
+		if function, ok := constant.(*Function); ok {
+			function.Chunk.Disassemble(w, function.String())
+		}
+	}
+}
+
+func (c *Chunk) disassembleInstruction(w io.Writer, offset int) int {
+	op := OpCode(c.Code[offset])
+	fmt.Fprintf(w, "%04d %4d %-14s", offset, c.TokenAt(offset).LineNumber, op)
+	switch op {
+	case OpConstant, OpGetGlobal, OpDefineGlobal, OpSetGlobal, OpClass, OpMethod, OpGetProperty, OpSetProperty, OpGetSuper:
+		index := c.readUint16(offset + 1)
+		fmt.Fprintf(w, " %4d '%v'\n", index, c.Constants[index])
+		return offset + 3
+	case OpList, OpMap, OpTemplate:
+		fmt.Fprintf(w, " %4d\n", c.readUint16(offset+1))
+		return offset + 3
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall:
+		fmt.Fprintf(w, " %4d\n", c.Code[offset+1])
+		return offset + 2
+	case OpCallNamed:
+		argCount, named := c.Code[offset+1], int(c.Code[offset+2])
+		names := make([]string, named)
+		for i := range names {
+			names[i] = fmt.Sprint(c.Constants[c.readUint16(offset+3+2*i)])
+		}
+		fmt.Fprintf(w, " %4d %v\n", argCount, names)
+		return offset + 3 + 2*named
+	case OpDefault:
+		fmt.Fprintf(w, " %4d -> %d\n", c.Code[offset+1], offset+4+c.readUint16(offset+2))
+		return offset + 4
+	case OpJump, OpJumpIfFalse, OpForNext, OpTry:
+		fmt.Fprintf(w, " %4d -> %d\n", offset, offset+3+c.readUint16(offset+1))
+		return offset + 3
+	case OpLoop:
+		fmt.Fprintf(w, " %4d -> %d\n", offset, offset+3-c.readUint16(offset+1))
+		return offset + 3
+	case OpClosure:
+		function := c.Constants[c.readUint16(offset+1)].(*Function)
+		fmt.Fprintf(w, " %s\n", function)
+		offset += 3
+		for i := 0; i < function.UpvalueCount; i++ {
+			kind := "upvalue"
+			if c.Code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(w, "%04d      |                     %s %d\n", offset, kind, c.Code[offset+1])
+			offset += 2
+		}
+		return offset
+	}
+	fmt.Fprintln(w)
+	return offset + 1
+}
+
+func (c *Chunk) readUint16(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
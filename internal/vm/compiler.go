@@ -0,0 +1,926 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
+	"github.com/aselhid/indoscript/internal/interpreter"
+)
+
+const (
+	maxLocals    = math.MaxUint8 + 1
+	maxUpvalues  = math.MaxUint8 + 1
+	maxArguments = math.MaxUint8
+	maxConstants = math.MaxUint16 + 1
+	maxJump      = math.MaxUint16
+)
+
+type local struct {
+	name string
+	// depth is the scope depth the local was declared in, -1 while its
+	// initializer is still being compiled
+	depth    int
+	captured bool
+}
+
+// loop collects the jumps of the berhenti and lanjut statements aimed at a
+// loop being compiled, they are patched once the targets are known.
+type loop struct {
+	label string
+	// breakDepth and continueDepth are the scope depths of the locals still
+	// alive where the loop ends and where its next iteration starts, deeper
+	// locals are discarded before jumping there
+	breakDepth    int
+	continueDepth int
+	breaks        []int
+	continues     []int
+	enclosing     *loop
+}
+
+// tryBlock is a coba statement whose handler is active at the code being
+// compiled. Jumping out of it ends the handler and runs the akhirnya block on
+// the way out.
+type tryBlock struct {
+	finally *ast.BlockStmt
+	// loop is the innermost loop around the coba statement
+	loop      *loop
+	enclosing *tryBlock
+}
+
+type functionKind uint8
+
+const (
+	kindFunction functionKind = iota
+	kindMethod
+	kindInitializer
+)
+
+type upvalueRef struct {
+	index   uint8
+	isLocal bool
+}
+
+// Compiler turns a resolved program into bytecode. Every fungsi gets its own
+// Compiler, chained through enclosing so variables of outer functions can be
+// captured as upvalues.
+type Compiler struct {
+	enclosing  *Compiler
+	function   *Function
+	kind       functionKind
+	locals     []local
+	upvalues   []upvalueRef
+	scopeDepth int
+	// loop is the innermost loop being compiled in this fungsi
+	loop *loop
+	// try is the innermost active coba handler in this fungsi
+	try *tryBlock
+	// constants maps names and literals to their index in the constant table
+	// so repeated uses share one entry
+	constants map[any]int
+	// token is the token instructions are currently attributed to, it is
+	// what runtime errors are reported at
+	token ast.Token
+}
+
+// Compile compiles stmts into the function run as the top level script. The
+// returned error is a diagnostic.Diagnostic when the program exceeds one of the
+// limits of the bytecode format.
+func Compile(stmts []ast.Stmt) (function *Function, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			diag, ok := r.(diagnostic.Diagnostic)
+			if !ok {
+				panic(r)
+			}
+			function, err = nil, diag
+		}
+	}()
+
+	c := newCompiler(nil, "", kindFunction)
+	c.statements(stmts)
+	return c.end(), nil
+}
+
+func newCompiler(enclosing *Compiler, name string, kind functionKind) *Compiler {
+	c := &Compiler{
+		enclosing: enclosing,
+		function:  &Function{Name: name},
+		kind:      kind,
+		constants: make(map[any]int),
+	}
+	if enclosing != nil {
+		c.token = enclosing.token
+	}
+	// slot zero holds the closure being called, or the objek a method was
+	// called on, which the method reads as ini
+	if kind == kindFunction {
+		c.locals = append(c.locals, local{depth: 0})
+	} else {
+		c.locals = append(c.locals, local{name: thisToken.Lexeme, depth: 0})
+	}
+	return c
+}
+
+// thisToken names the local holding the objek in a method, superToken the one
+// holding the induk kelas around the methods of a kelas that inherits.
+var (
+	thisToken  = ast.Token{TokenType: ast.TokenThis, Lexeme: "ini"}
+	superToken = ast.Token{TokenType: ast.TokenSuper, Lexeme: "induk"}
+)
+
+func (c *Compiler) end() *Function {
+	c.emitReturn()
+	return c.function
+}
+
+// emitReturn returns from a fungsi without a value, an initializer returns
+// its objek instead.
+func (c *Compiler) emitReturn() {
+	if c.kind == kindInitializer {
+		c.emitOp(OpGetLocal)
+		c.emitByte(0)
+	} else {
+		c.emitOp(OpNil)
+	}
+	c.returnValue()
+}
+
+// returnValue returns the value on top of the stack. Active coba handlers are
+// ended first and their akhirnya blocks run while the value waits in a slot
+// of its own.
+func (c *Compiler) returnValue() {
+	if c.try != nil {
+		c.beginScope()
+		c.declareVariable(ast.Token{})
+		c.markInitialized()
+		for t := c.try; t != nil; t = t.enclosing {
+			c.leaveTry(t)
+		}
+		// the slot is left on the stack for OpReturn to take
+		c.scopeDepth--
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+	c.emitOp(OpReturn)
+}
+
+func (c *Compiler) statements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		c.statement(stmt)
+	}
+}
+
+func (c *Compiler) statement(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case ast.PrintStmt:
+		c.expression(s.Expression)
+		c.emitOp(OpPrint)
+	case ast.ExprStmt:
+		c.expression(s.Expression)
+		c.emitOp(OpPop)
+	case ast.VarStmt:
+		c.token = s.Identifier
+		c.declareVariable(s.Identifier)
+		c.expression(s.Expression)
+		c.token = s.Identifier
+		c.defineVariable(s.Identifier)
+	case ast.AssignStmt:
+		c.expression(s.Expression)
+		c.token = s.Identifier
+		c.setVariable(s.Identifier)
+	case ast.IndexAssignStmt:
+		c.expression(s.Object)
+		c.expression(s.Index)
+		c.expression(s.Expression)
+		c.token = s.Bracket
+		c.emitOp(OpSetIndex)
+	case ast.BlockStmt:
+		c.block(s)
+	case ast.IfStmt:
+		c.expression(s.Condition)
+		thenJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+		c.block(s.ThenStmt)
+		elseJump := c.emitJump(OpJump)
+		c.patchJump(thenJump)
+		c.emitOp(OpPop)
+		if s.HasElse() {
+			c.statement(s.ElseStmt)
+		}
+		c.patchJump(elseJump)
+	case ast.WhileStmt:
+		l := c.beginLoop(s.Label, c.scopeDepth, c.scopeDepth)
+		loopStart := len(c.function.Chunk.Code)
+		c.expression(s.Condition)
+		exitJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+		// the body is its own scope so every iteration gets fresh variables
+		// for closures to capture
+		c.block(s.Stmt)
+		c.patchJumps(l.continues)
+		c.emitLoop(loopStart)
+		c.patchJump(exitJump)
+		c.emitOp(OpPop)
+		c.endLoop(l)
+	case ast.ForStmt:
+		c.forStmt(s)
+	case ast.ForInStmt:
+		c.beginScope()
+		c.expression(s.Iterable)
+		c.token = s.Keyword
+		c.emitOp(OpIterate)
+		// the iterator stays on the stack as a hidden local while looping
+		c.declareVariable(ast.Token{Lexeme: "(iterator)"})
+		c.markInitialized()
+		l := c.beginLoop(s.Label, c.scopeDepth, c.scopeDepth)
+		loopStart := len(c.function.Chunk.Code)
+		exitJump := c.emitJump(OpForNext)
+		c.beginScope()
+		c.declareVariable(s.Variable)
+		c.markInitialized()
+		c.block(s.Body)
+		c.endScope()
+		c.patchJumps(l.continues)
+		c.emitLoop(loopStart)
+		c.patchJump(exitJump)
+		c.endLoop(l)
+		c.endScope()
+	case ast.FuncStmt:
+		c.token = s.Name
+		c.declareVariable(s.Name)
+		// a local function is initialized before its body is compiled so it
+		// can call itself
+		c.markInitialized()
+		c.compileFunction(interpreter.NewSignature(s), s, kindFunction)
+		c.token = s.Name
+		c.defineVariable(s.Name)
+	case ast.ClassStmt:
+		c.classStmt(s)
+	case ast.ReturnStmt:
+		if s.Value == nil {
+			c.token = s.Keyword
+			c.emitReturn()
+			break
+		}
+		c.expression(s.Value)
+		c.token = s.Keyword
+		c.returnValue()
+	case ast.PropertyAssignStmt:
+		c.expression(s.Object)
+		c.expression(s.Expression)
+		c.token = s.Name
+		c.emitOp(OpSetProperty)
+		c.emitUint16(c.makeConstant(s.Name.Lexeme))
+	case ast.BreakStmt:
+		c.token = s.Keyword
+		l := c.findLoop(s.Label)
+		c.leaveTries(l)
+		c.discardLocals(l.breakDepth)
+		l.breaks = append(l.breaks, c.emitJump(OpJump))
+	case ast.ContinueStmt:
+		c.token = s.Keyword
+		l := c.findLoop(s.Label)
+		c.leaveTries(l)
+		c.discardLocals(l.continueDepth)
+		l.continues = append(l.continues, c.emitJump(OpJump))
+	case ast.ThrowStmt:
+		c.expression(s.Value)
+		c.token = s.Keyword
+		c.emitOp(OpThrow)
+	case ast.TryStmt:
+		c.tryStmt(s)
+	default:
+		panic(fmt.Sprintf("vm: unexpected statement %T", stmt))
+	}
+}
+
+// forStmt compiles a C-style untuk loop. The body works on copies of the
+// variables declared by the initializer, which are written back before the
+// increment runs, so closures created in the body capture the values of their
+// own iteration.
+func (c *Compiler) forStmt(s ast.ForStmt) {
+	c.beginScope()
+	first := len(c.locals)
+	if s.Initializer != nil {
+		c.statement(s.Initializer)
+	}
+	variables := len(c.locals) - first
+
+	l := c.beginLoop(s.Label, c.scopeDepth, c.scopeDepth+1)
+	loopStart := len(c.function.Chunk.Code)
+	exitJump := -1
+	if s.Condition != nil {
+		c.expression(s.Condition)
+		exitJump = c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+	}
+
+	c.beginScope()
+	for i := 0; i < variables; i++ {
+		c.emitOp(OpGetLocal)
+		c.emitByte(uint8(first + i))
+		c.declareVariable(ast.Token{Lexeme: c.locals[first+i].name})
+		c.markInitialized()
+	}
+	c.block(s.Body)
+	c.patchJumps(l.continues)
+	for i := 0; i < variables; i++ {
+		c.emitOp(OpGetLocal)
+		c.emitByte(uint8(first + variables + i))
+		c.emitOp(OpSetLocal)
+		c.emitByte(uint8(first + i))
+	}
+	c.endScope()
+
+	if s.Increment != nil {
+		c.statement(s.Increment)
+	}
+	c.emitLoop(loopStart)
+	if exitJump != -1 {
+		c.patchJump(exitJump)
+		c.emitOp(OpPop)
+	}
+	c.endLoop(l)
+	c.endScope()
+}
+
+// tryStmt compiles a coba statement. The akhirnya block is compiled once for
+// every way out of the statement: after the body, after the tangkap block,
+// before rethrowing an error and before every jump out of the handlers.
+func (c *Compiler) tryStmt(s ast.TryStmt) {
+	c.token = s.Keyword
+	handler := c.emitJump(OpTry)
+	c.beginTry(s.Finally)
+	c.block(s.Body)
+	c.endTry()
+	c.emitOp(OpEndTry)
+	c.finally(s.Finally)
+	ends := []int{c.emitJump(OpJump)}
+
+	// the handler starts with the caught runtime error on top of the stack
+	c.patchJump(handler)
+	c.beginScope()
+	if s.Catch == nil {
+		c.rethrow(s.Finally)
+		c.endScope()
+		c.patchJumps(ends)
+		return
+	}
+
+	c.token = s.CatchName
+	c.emitOp(OpCatch)
+	c.declareVariable(s.CatchName)
+	c.markInitialized()
+	rethrow := -1
+	if s.Finally != nil {
+		// errors raised by the tangkap block still run the akhirnya block
+		rethrow = c.emitJump(OpTry)
+		c.beginTry(s.Finally)
+	}
+	c.statements(s.Catch.Statements)
+	if s.Finally != nil {
+		c.endTry()
+		c.emitOp(OpEndTry)
+	}
+	c.endScope()
+	c.finally(s.Finally)
+
+	if rethrow != -1 {
+		ends = append(ends, c.emitJump(OpJump))
+		c.patchJump(rethrow)
+		// the galat is still below the new error
+		c.beginScope()
+		c.declareVariable(ast.Token{})
+		c.markInitialized()
+		c.rethrow(s.Finally)
+		c.endScope()
+	}
+	c.patchJumps(ends)
+}
+
+// rethrow runs finally and raises the runtime error on top of the stack again.
+func (c *Compiler) rethrow(finally *ast.BlockStmt) {
+	c.declareVariable(ast.Token{})
+	c.markInitialized()
+	c.finally(finally)
+	c.emitOp(OpThrow)
+}
+
+func (c *Compiler) finally(finally *ast.BlockStmt) {
+	if finally != nil {
+		c.block(*finally)
+	}
+}
+
+func (c *Compiler) beginTry(finally *ast.BlockStmt) {
+	c.try = &tryBlock{finally: finally, loop: c.loop, enclosing: c.try}
+}
+
+func (c *Compiler) endTry() {
+	c.try = c.try.enclosing
+}
+
+// leaveTry ends the handler of t before jumping out of it. Its akhirnya block
+// is compiled as if it stood right outside the coba statement.
+func (c *Compiler) leaveTry(t *tryBlock) {
+	c.emitOp(OpEndTry)
+	if t.finally == nil {
+		return
+	}
+	try, l := c.try, c.loop
+	c.try, c.loop = t.enclosing, t.loop
+	c.block(*t.finally)
+	c.try, c.loop = try, l
+}
+
+// leaveTries leaves the coba statements inside the loop l before a berhenti
+// or lanjut jumps to it.
+func (c *Compiler) leaveTries(l *loop) {
+	for t := c.try; t != nil && encloses(l, t.loop); t = t.enclosing {
+		c.leaveTry(t)
+	}
+}
+
+// encloses reports whether outer is inner or one of its enclosing loops.
+func encloses(outer, inner *loop) bool {
+	for ; inner != nil; inner = inner.enclosing {
+		if inner == outer {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Compiler) beginLoop(label ast.Token, breakDepth, continueDepth int) *loop {
+	c.loop = &loop{
+		label:         label.Lexeme,
+		breakDepth:    breakDepth,
+		continueDepth: continueDepth,
+		enclosing:     c.loop,
+	}
+	return c.loop
+}
+
+// endLoop makes the berhenti statements of l jump to the current position.
+func (c *Compiler) endLoop(l *loop) {
+	c.patchJumps(l.breaks)
+	c.loop = l.enclosing
+}
+
+// findLoop returns the loop named label, or the innermost loop for the zero
+// Token. The parser already rejected berhenti and lanjut without such a loop.
+func (c *Compiler) findLoop(label ast.Token) *loop {
+	l := c.loop
+	for label.Lexeme != "" && l.label != label.Lexeme {
+		l = l.enclosing
+	}
+	return l
+}
+
+// discardLocals pops the locals deeper than depth off the stack without
+// ending their scopes, code after a jump out of those scopes still sees them.
+// A local may only be captured by code compiled after the jump, so every local
+// is closed rather than popped.
+func (c *Compiler) discardLocals(depth int) {
+	for i := len(c.locals) - 1; i > 0 && c.locals[i].depth > depth; i-- {
+		c.emitOp(OpCloseUpvalue)
+	}
+}
+
+func (c *Compiler) block(stmt ast.BlockStmt) {
+	c.beginScope()
+	c.statements(stmt.Statements)
+	c.endScope()
+}
+
+// classStmt creates the kelas and adds its methods to it one by one while it
+// sits on top of the stack.
+func (c *Compiler) classStmt(s ast.ClassStmt) {
+	c.token = s.Name
+	c.declareVariable(s.Name)
+	c.emitOp(OpClass)
+	c.emitUint16(c.makeConstant(s.Name.Lexeme))
+	c.defineVariable(s.Name)
+
+	if s.Superclass != nil {
+		c.token = s.Superclass.Identifier
+		c.getVariable(s.Superclass.Identifier)
+		// the induk kelas stays on the stack as a local the methods capture
+		c.beginScope()
+		c.declareVariable(superToken)
+		c.markInitialized()
+		c.getVariable(s.Name)
+		c.emitOp(OpInherit)
+	}
+
+	c.getVariable(s.Name)
+	for _, method := range s.Methods {
+		signature, kind := interpreter.NewSignature(method), kindMethod
+		if method.Name.Lexeme == ast.InitializerName {
+			// errors about the arguments of the initializer name the kelas
+			// the script called
+			signature.Name, kind = s.Name.Lexeme, kindInitializer
+		}
+		c.compileFunction(signature, method, kind)
+		c.token = method.Name
+		c.emitOp(OpMethod)
+		c.emitUint16(c.makeConstant(method.Name.Lexeme))
+	}
+	c.emitOp(OpPop)
+	if s.Superclass != nil {
+		c.endScope()
+	}
+}
+
+func (c *Compiler) compileFunction(signature interpreter.Signature, stmt ast.FuncStmt, kind functionKind) {
+	compiler := newCompiler(c, signature.Name, kind)
+	compiler.function.Signature = signature
+	compiler.beginScope()
+	for i, parameter := range stmt.Parameters {
+		compiler.token = parameter
+		if stmt.Defaults[i] != nil {
+			// the default is compiled before the parameter is declared so
+			// it only sees the parameters before it, like in the resolver
+			compiler.defaultValue(uint8(len(compiler.locals)), stmt.Defaults[i])
+		}
+		compiler.declareVariable(parameter)
+		compiler.markInitialized()
+	}
+	compiler.statements(stmt.Body)
+	function := compiler.end()
+	function.UpvalueCount = len(compiler.upvalues)
+
+	c.token = stmt.Name
+	if stmt.Name.Lexeme == "" {
+		c.token = stmt.Keyword
+	}
+	c.emitOp(OpClosure)
+	c.emitUint16(c.makeConstant(function))
+	for _, upvalue := range compiler.upvalues {
+		if upvalue.isLocal {
+			c.emitByte(1)
+		} else {
+			c.emitByte(0)
+		}
+		c.emitByte(upvalue.index)
+	}
+}
+
+// defaultValue stores the value of expr in the parameter at slot when the
+// call gave no argument for it.
+func (c *Compiler) defaultValue(slot uint8, expr ast.Expr) {
+	c.emitOp(OpDefault)
+	c.emitByte(slot)
+	c.emitByte(0xff)
+	c.emitByte(0xff)
+	jump := len(c.function.Chunk.Code) - 2
+	c.expression(expr)
+	c.emitOp(OpSetLocal)
+	c.emitByte(slot)
+	c.patchJump(jump)
+}
+
+func (c *Compiler) expression(expr ast.Expr) {
+	switch e := expr.(type) {
+	case ast.BinaryExpr:
+		c.expression(e.Left)
+		c.expression(e.Right)
+		c.token = e.Operator
+		c.binaryOp(e.Operator)
+	case ast.LogicalExpr:
+		c.expression(e.Left)
+		if e.Operator.TokenType == ast.TokenOr {
+			elseJump := c.emitJump(OpJumpIfFalse)
+			endJump := c.emitJump(OpJump)
+			c.patchJump(elseJump)
+			c.emitOp(OpPop)
+			c.expression(e.Right)
+			c.patchJump(endJump)
+		} else {
+			endJump := c.emitJump(OpJumpIfFalse)
+			c.emitOp(OpPop)
+			c.expression(e.Right)
+			c.patchJump(endJump)
+		}
+	case ast.UnaryExpr:
+		c.expression(e.Right)
+		c.token = e.Operator
+		switch e.Operator.TokenType {
+		case ast.TokenMinus:
+			c.emitOp(OpNegate)
+		case ast.TokenBang:
+			c.emitOp(OpNot)
+		}
+	case ast.PrimaryExpr:
+		switch e.Literal {
+		case nil:
+			c.emitOp(OpNil)
+		case true:
+			c.emitOp(OpTrue)
+		case false:
+			c.emitOp(OpFalse)
+		default:
+			c.emitConstant(e.Literal)
+		}
+	case ast.GroupExpr:
+		c.expression(e.Expression)
+	case ast.VarExpr:
+		c.token = e.Identifier
+		c.getVariable(e.Identifier)
+	case ast.FuncExpr:
+		c.compileFunction(interpreter.NewSignature(e.Declaration), e.Declaration, kindFunction)
+	case ast.CallExpr:
+		c.expression(e.Callee)
+		if len(e.Arguments) > maxArguments {
+			c.error(e.Parenthesis, fmt.Sprintf("can't have more than %d arguments", maxArguments))
+		}
+		for _, argument := range e.Arguments {
+			c.expression(argument)
+		}
+		c.token = e.Parenthesis
+		if e.Names == nil {
+			c.emitOp(OpCall)
+			c.emitByte(uint8(len(e.Arguments)))
+			break
+		}
+		var named []ast.Token
+		for _, name := range e.Names {
+			if name.Lexeme != "" {
+				named = append(named, name)
+			}
+		}
+		c.emitOp(OpCallNamed)
+		c.emitByte(uint8(len(e.Arguments)))
+		c.emitByte(uint8(len(named)))
+		for _, name := range named {
+			c.emitUint16(c.makeConstant(name.Lexeme))
+		}
+	case ast.ListExpr:
+		if len(e.Elements) > math.MaxUint16 {
+			c.error(e.Bracket, "too many elements in a list literal")
+		}
+		for _, element := range e.Elements {
+			c.expression(element)
+		}
+		c.token = e.Bracket
+		c.emitOp(OpList)
+		c.emitUint16(len(e.Elements))
+	case ast.TemplateExpr:
+		// the segments and values alternate on the stack, empty segments are
+		// left out
+		parts := 0
+		for i, segment := range e.Segments {
+			if text := segment.Literal.(string); text != "" {
+				c.token = segment
+				c.emitConstant(text)
+				parts++
+			}
+			if i < len(e.Values) {
+				c.expression(e.Values[i])
+				parts++
+			}
+		}
+		if parts > math.MaxUint16 {
+			c.error(e.Segments[0], "too many interpolated expressions in a teks")
+		}
+		c.token = e.Segments[0]
+		c.emitOp(OpTemplate)
+		c.emitUint16(parts)
+	case ast.IndexExpr:
+		c.expression(e.Object)
+		c.expression(e.Index)
+		c.token = e.Bracket
+		c.emitOp(OpIndex)
+	case ast.PropertyExpr:
+		c.expression(e.Object)
+		c.token = e.Name
+		c.emitOp(OpGetProperty)
+		c.emitUint16(c.makeConstant(e.Name.Lexeme))
+	case ast.ThisExpr:
+		c.token = e.Keyword
+		c.getVariable(e.Keyword)
+	case ast.SuperExpr:
+		c.token = e.Keyword
+		c.getVariable(thisToken)
+		c.getVariable(e.Keyword)
+		c.token = e.Method
+		c.emitOp(OpGetSuper)
+		c.emitUint16(c.makeConstant(e.Method.Lexeme))
+	case ast.MapExpr:
+		if len(e.Keys) > math.MaxUint16 {
+			c.error(e.Brace, "too many entries in a map literal")
+		}
+		for i := range e.Keys {
+			c.expression(e.Keys[i])
+			c.expression(e.Values[i])
+		}
+		c.token = e.Brace
+		c.emitOp(OpMap)
+		c.emitUint16(len(e.Keys))
+	default:
+		panic(fmt.Sprintf("vm: unexpected expression %T", expr))
+	}
+}
+
+func (c *Compiler) binaryOp(operator ast.Token) {
+	switch operator.TokenType {
+	case ast.TokenPlus:
+		c.emitOp(OpAdd)
+	case ast.TokenMinus:
+		c.emitOp(OpSubtract)
+	case ast.TokenStar:
+		c.emitOp(OpMultiply)
+	case ast.TokenSlash:
+		c.emitOp(OpDivide)
+	case ast.TokenGreater:
+		c.emitOp(OpGreater)
+	case ast.TokenGreaterEqual:
+		c.emitOp(OpGreaterEqual)
+	case ast.TokenLess:
+		c.emitOp(OpLess)
+	case ast.TokenLessEqual:
+		c.emitOp(OpLessEqual)
+	case ast.TokenEqualEqual:
+		c.emitOp(OpEqual)
+	case ast.TokenBangEqual:
+		c.emitOp(OpNotEqual)
+	default:
+		panic(fmt.Sprintf("vm: unexpected binary operator %v", operator.TokenType))
+	}
+}
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		if c.locals[len(c.locals)-1].captured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+// declareVariable adds a local for name when compiling inside a scope, globals
+// are late bound by name and need no declaration.
+func (c *Compiler) declareVariable(name ast.Token) {
+	if c.scopeDepth == 0 {
+		return
+	}
+	if len(c.locals) == maxLocals {
+		c.error(name, "too many local variables in fungsi")
+	}
+	c.locals = append(c.locals, local{name: name.Lexeme, depth: -1})
+}
+
+func (c *Compiler) markInitialized() {
+	if c.scopeDepth == 0 {
+		return
+	}
+	c.locals[len(c.locals)-1].depth = c.scopeDepth
+}
+
+// defineVariable binds the value on top of the stack to name. A local already
+// lives in its slot, so only globals need an instruction.
+func (c *Compiler) defineVariable(name ast.Token) {
+	if c.scopeDepth > 0 {
+		c.markInitialized()
+		return
+	}
+	c.emitOp(OpDefineGlobal)
+	c.emitUint16(c.makeConstant(name.Lexeme))
+}
+
+func (c *Compiler) getVariable(name ast.Token) {
+	if slot, ok := c.resolveLocal(name.Lexeme); ok {
+		c.emitOp(OpGetLocal)
+		c.emitByte(slot)
+	} else if index, ok := c.resolveUpvalue(name.Lexeme); ok {
+		c.emitOp(OpGetUpvalue)
+		c.emitByte(index)
+	} else {
+		c.emitOp(OpGetGlobal)
+		c.emitUint16(c.makeConstant(name.Lexeme))
+	}
+}
+
+func (c *Compiler) setVariable(name ast.Token) {
+	if slot, ok := c.resolveLocal(name.Lexeme); ok {
+		c.emitOp(OpSetLocal)
+		c.emitByte(slot)
+	} else if index, ok := c.resolveUpvalue(name.Lexeme); ok {
+		c.emitOp(OpSetUpvalue)
+		c.emitByte(index)
+	} else {
+		c.emitOp(OpSetGlobal)
+		c.emitUint16(c.makeConstant(name.Lexeme))
+	}
+}
+
+func (c *Compiler) resolveLocal(name string) (uint8, bool) {
+	// slot zero is only found when it holds ini, it has no name otherwise
+	for i := len(c.locals) - 1; i >= 0; i-- {
+		if c.locals[i].name == name {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+func (c *Compiler) resolveUpvalue(name string) (uint8, bool) {
+	if c.enclosing == nil {
+		return 0, false
+	}
+	if slot, ok := c.enclosing.resolveLocal(name); ok {
+		c.enclosing.locals[slot].captured = true
+		return c.addUpvalue(slot, true), true
+	}
+	if index, ok := c.enclosing.resolveUpvalue(name); ok {
+		return c.addUpvalue(index, false), true
+	}
+	return 0, false
+}
+
+func (c *Compiler) addUpvalue(index uint8, isLocal bool) uint8 {
+	for i, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return uint8(i)
+		}
+	}
+	if len(c.upvalues) == maxUpvalues {
+		c.error(c.token, "too many captured variables in fungsi")
+	}
+	c.upvalues = append(c.upvalues, upvalueRef{index: index, isLocal: isLocal})
+	return uint8(len(c.upvalues) - 1)
+}
+
+func (c *Compiler) makeConstant(value any) int {
+	if index, ok := c.constants[value]; ok {
+		return index
+	}
+	constants := c.function.Chunk.Constants
+	if len(constants) == maxConstants {
+		c.error(c.token, "too many constants in one chunk")
+	}
+	c.function.Chunk.Constants = append(constants, value)
+	c.constants[value] = len(constants)
+	return len(constants)
+}
+
+func (c *Compiler) emitConstant(value any) {
+	c.emitOp(OpConstant)
+	c.emitUint16(c.makeConstant(value))
+}
+
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitOp(op)
+	c.emitByte(0xff)
+	c.emitByte(0xff)
+	return len(c.function.Chunk.Code) - 2
+}
+
+func (c *Compiler) patchJump(offset int) {
+	jump := len(c.function.Chunk.Code) - offset - 2
+	if jump > maxJump {
+		c.error(c.token, "too much code to jump over")
+	}
+	c.function.Chunk.Code[offset] = byte(jump >> 8)
+	c.function.Chunk.Code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) patchJumps(offsets []int) {
+	for _, offset := range offsets {
+		c.patchJump(offset)
+	}
+}
+
+func (c *Compiler) emitLoop(loopStart int) {
+	c.emitOp(OpLoop)
+	offset := len(c.function.Chunk.Code) - loopStart + 2
+	if offset > maxJump {
+		c.error(c.token, "loop body too large")
+	}
+	c.emitUint16(offset)
+}
+
+func (c *Compiler) emitOp(op OpCode) {
+	c.emitByte(byte(op))
+}
+
+func (c *Compiler) emitByte(b byte) {
+	c.function.Chunk.write(b, c.token)
+}
+
+func (c *Compiler) emitUint16(value int) {
+	c.emitByte(byte(value >> 8))
+	c.emitByte(byte(value))
+}
+
+func (c *Compiler) error(token ast.Token, message string) {
+	panic(diagnostic.NewError(token.Span(), diagnostic.CodeCompile, message))
+}
@@ -0,0 +1,120 @@
+package vm
+
+type OpCode uint8
+
+// Operands are written right after the opcode. u8 and u16 operands are one
+// and two bytes wide, u16 operands are big endian.
+const (
+	OpConstant     OpCode = iota // u16 constant index
+	OpNil                        //
+	OpTrue                       //
+	OpFalse                      //
+	OpPop                        //
+	OpGetLocal                   // u8 slot
+	OpSetLocal                   // u8 slot
+	OpGetUpvalue                 // u8 upvalue index
+	OpSetUpvalue                 // u8 upvalue index
+	OpGetGlobal                  // u16 name constant
+	OpDefineGlobal               // u16 name constant
+	OpSetGlobal                  // u16 name constant
+	OpEqual                      //
+	OpNotEqual                   //
+	OpGreater                    //
+	OpGreaterEqual               //
+	OpLess                       //
+	OpLessEqual                  //
+	OpAdd                        //
+	OpSubtract                   //
+	OpMultiply                   //
+	OpDivide                     //
+	OpNot                        //
+	OpNegate                     //
+	OpPrint                      //
+	OpJump                       // u16 forward offset
+	OpJumpIfFalse                // u16 forward offset, leaves the condition on the stack
+	OpLoop                       // u16 backward offset
+	OpCall                       // u8 argument count
+	OpCallNamed                  // u8 argument count, u8 named count, then u16 name constant per named argument
+	OpClosure                    // u16 function constant, then u8 isLocal and u8 index per upvalue
+	OpCloseUpvalue               //
+	OpReturn                     //
+	OpList                       // u16 element count
+	OpMap                        // u16 entry count
+	OpIndex                      //
+	OpSetIndex                   //
+	OpDefault                    // u8 parameter slot, u16 forward offset taken unless the parameter is missing
+	OpIterate                    //
+	OpForNext                    // u16 forward offset taken once the iterator on top of the stack is exhausted
+	OpClass                      // u16 name constant
+	OpMethod                     // u16 name constant, adds the closure on top of the stack to the kelas below it
+	OpGetProperty                // u16 name constant
+	OpSetProperty                // u16 name constant
+	OpInherit                    // copies the methods of the induk kelas below the kelas on top of the stack
+	OpGetSuper                   // u16 name constant, binds the method of the induk kelas on top of the stack to the objek below it
+	OpTry                        // u16 forward offset of the handler a runtime error jumps to until OpEndTry
+	OpEndTry                     //
+	OpCatch                      // turns the runtime error on top of the stack into a galat
+	OpThrow                      //
+	OpTemplate                   // u16 part count, joins the parts on top of the stack into one teks
+)
+
+var opNames = [...]string{
+	OpConstant:     "CONSTANT",
+	OpNil:          "NIL",
+	OpTrue:         "TRUE",
+	OpFalse:        "FALSE",
+	OpPop:          "POP",
+	OpGetLocal:     "GET_LOCAL",
+	OpSetLocal:     "SET_LOCAL",
+	OpGetUpvalue:   "GET_UPVALUE",
+	OpSetUpvalue:   "SET_UPVALUE",
+	OpGetGlobal:    "GET_GLOBAL",
+	OpDefineGlobal: "DEFINE_GLOBAL",
+	OpSetGlobal:    "SET_GLOBAL",
+	OpEqual:        "EQUAL",
+	OpNotEqual:     "NOT_EQUAL",
+	OpGreater:      "GREATER",
+	OpGreaterEqual: "GREATER_EQUAL",
+	OpLess:         "LESS",
+	OpLessEqual:    "LESS_EQUAL",
+	OpAdd:          "ADD",
+	OpSubtract:     "SUBTRACT",
+	OpMultiply:     "MULTIPLY",
+	OpDivide:       "DIVIDE",
+	OpNot:          "NOT",
+	OpNegate:       "NEGATE",
+	OpPrint:        "PRINT",
+	OpJump:         "JUMP",
+	OpJumpIfFalse:  "JUMP_IF_FALSE",
+	OpLoop:         "LOOP",
+	OpCall:         "CALL",
+	OpCallNamed:    "CALL_NAMED",
+	OpClosure:      "CLOSURE",
+	OpCloseUpvalue: "CLOSE_UPVALUE",
+	OpReturn:       "RETURN",
+	OpList:         "LIST",
+	OpMap:          "MAP",
+	OpIndex:        "INDEX",
+	OpSetIndex:     "SET_INDEX",
+	OpDefault:      "DEFAULT",
+	OpIterate:      "ITERATE",
+	OpForNext:      "FOR_NEXT",
+	OpClass:        "CLASS",
+	OpMethod:       "METHOD",
+	OpGetProperty:  "GET_PROPERTY",
+	OpSetProperty:  "SET_PROPERTY",
+	OpInherit:      "INHERIT",
+	OpGetSuper:     "GET_SUPER",
+	OpTry:          "TRY",
+	OpEndTry:       "END_TRY",
+	OpCatch:        "CATCH",
+	OpThrow:        "THROW",
+	OpTemplate:     "TEMPLATE",
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opNames) && opNames[op] != "" {
+		return opNames[op]
+	}
+	return "UNKNOWN"
+}
@@ -0,0 +1,75 @@
+package vm
+
+import "github.com/aselhid/indoscript/internal/interpreter"
+
+// Function is a compiled fungsi, or the top level script when Name is empty.
+type Function struct {
+	Name string
+	// Signature matches the arguments of a call to the parameters, which are
+	// the locals right after the callee
+	Signature    interpreter.Signature
+	UpvalueCount int
+	Chunk        Chunk
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<skrip>"
+	}
+	return "<fungsi " + f.Name + ">"
+}
+
+// Closure is the runtime value of a fungsi, it pairs the compiled function
+// with the variables it captured from enclosing functions.
+type Closure struct {
+	Function *Function
+	Upvalues []*Upvalue
+}
+
+// Arity reports the parameters without a default value.
+func (c *Closure) Arity() (int, bool) {
+	return c.Function.Signature.Required, c.Function.Signature.Variadic
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+// Class is the runtime value of a kelas.
+type Class struct {
+	Name    string
+	Methods map[string]*Closure
+}
+
+func (c *Class) ClassName() string {
+	return c.Name
+}
+
+func (c *Class) String() string {
+	return "<kelas " + c.Name + ">"
+}
+
+// BoundMethod is a method read from an objek, calling it runs the method with
+// the objek as ini.
+type BoundMethod struct {
+	Receiver *interpreter.Instance
+	Method   *Closure
+}
+
+func (b *BoundMethod) Arity() (int, bool) {
+	return b.Method.Arity()
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
+
+// Upvalue is a variable captured by a closure. While the variable is still
+// alive on the stack the upvalue points at its slot, once the variable goes out
+// of scope the value is moved into the upvalue itself.
+type Upvalue struct {
+	slot   int
+	open   bool
+	closed any
+	next   *Upvalue
+}
@@ -0,0 +1,587 @@
+// Package vm runs indoscript programs compiled to bytecode on a stack machine.
+// It is an alternative to the tree walking interpreter and behaves the same,
+// runtime values and their helpers are shared with the interpreter package.
+package vm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/errors"
+	"github.com/aselhid/indoscript/internal/interpreter"
+)
+
+const (
+	// maxFrames bounds the depth of fungsi calls so runaway recursion is a
+	// runtime error instead of exhausting memory
+	maxFrames = 10000
+	// interruptInterval is how many calls and loop iterations run between
+	// checks of the context
+	interruptInterval = 1024
+)
+
+type callFrame struct {
+	closure *Closure
+	ip      int
+	// base is the stack index of slot zero of the frame
+	base int
+}
+
+// handler is an active coba block. A runtime error unwinds the frames and the
+// stack to where they were when the block began and continues at ip.
+type handler struct {
+	frames int
+	stack  int
+	ip     int
+}
+
+type VM struct {
+	stdOut       io.Writer
+	globals      map[string]any
+	stack        []any
+	frames       []callFrame
+	handlers     []handler
+	openUpvalues *Upvalue
+	ctx          context.Context
+	ticks        int
+}
+
+// interruption is panicked when the context given to Run is done.
+type interruption struct {
+	err error
+}
+
+func NewVM(stdOut io.Writer) *VM {
+	vm := &VM{
+		stdOut:  stdOut,
+		globals: make(map[string]any),
+		ctx:     context.Background(),
+	}
+	for _, native := range interpreter.Builtins() {
+		vm.globals[native.Name] = native
+	}
+	return vm
+}
+
+// Define binds a global variable, it is how hosts hand values to a script
+// before running it.
+func (vm *VM) Define(name string, value any) {
+	vm.globals[name] = value
+}
+
+// Run executes the top level function returned by Compile until it finishes,
+// a runtime error happens or ctx is done. The returned error is either an
+// errors.RuntimeError or ctx.Err().
+func (vm *VM) Run(ctx context.Context, function *Function) (runtimeErr error) {
+	vm.ctx = ctx
+	defer func() {
+		if err := recover(); err != nil {
+			switch e := err.(type) {
+			case errors.RuntimeError:
+				runtimeErr = vm.traced(e)
+			case interruption:
+				runtimeErr = e.err
+			default:
+				runtimeErr = fmt.Errorf("internal error: %v", err)
+			}
+		}
+		vm.stack = vm.stack[:0]
+		vm.frames = vm.frames[:0]
+		vm.handlers = vm.handlers[:0]
+		vm.openUpvalues = nil
+	}()
+
+	closure := &Closure{Function: function}
+	vm.push(closure)
+	vm.call(closure, 0, nil, ast.Token{})
+	for vm.resume() {
+	}
+	return nil
+}
+
+// resume runs the script until it finishes, it reports true when a runtime
+// error was caught and running continues at the handler that caught it.
+func (vm *VM) resume() (caught bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			e, ok := err.(errors.RuntimeError)
+			if !ok || len(vm.handlers) == 0 {
+				panic(err)
+			}
+			vm.catch(e)
+			caught = true
+		}
+	}()
+	vm.run()
+	return false
+}
+
+// catch unwinds to the innermost handler and pushes err for it.
+func (vm *VM) catch(err errors.RuntimeError) {
+	err = vm.traced(err)
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.closeUpvalues(h.stack)
+	vm.frames = vm.frames[:h.frames]
+	vm.stack = vm.stack[:h.stack]
+	vm.frames[len(vm.frames)-1].ip = h.ip
+	vm.push(err)
+}
+
+// traced gives a runtime error the stack trace as it is now, unless it
+// already has one from where it was first raised.
+func (vm *VM) traced(err errors.RuntimeError) errors.RuntimeError {
+	if err.Trace() == nil {
+		return err.WithTrace(vm.stackTrace(err.Token()))
+	}
+	return err
+}
+
+func (vm *VM) run() {
+	frame := &vm.frames[len(vm.frames)-1]
+	code := frame.closure.Function.Chunk.Code
+
+	readByte := func() byte {
+		b := code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readUint16 := func() int {
+		frame.ip += 2
+		return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+	}
+	readConstant := func() any {
+		return frame.closure.Function.Chunk.Constants[readUint16()]
+	}
+
+	for {
+		switch op := OpCode(readByte()); op {
+		case OpConstant:
+			vm.push(readConstant())
+		case OpNil:
+			vm.push(nil)
+		case OpTrue:
+			vm.push(true)
+		case OpFalse:
+			vm.push(false)
+		case OpPop:
+			vm.pop()
+		case OpGetLocal:
+			vm.push(vm.stack[frame.base+int(readByte())])
+		case OpSetLocal:
+			vm.stack[frame.base+int(readByte())] = vm.pop()
+		case OpGetUpvalue:
+			upvalue := frame.closure.Upvalues[readByte()]
+			if upvalue.open {
+				vm.push(vm.stack[upvalue.slot])
+			} else {
+				vm.push(upvalue.closed)
+			}
+		case OpSetUpvalue:
+			upvalue := frame.closure.Upvalues[readByte()]
+			if upvalue.open {
+				vm.stack[upvalue.slot] = vm.pop()
+			} else {
+				upvalue.closed = vm.pop()
+			}
+		case OpGetGlobal:
+			name := readConstant().(string)
+			value, ok := vm.globals[name]
+			if !ok {
+				vm.error(frame, fmt.Sprintf("Undefined variable %s", name))
+			}
+			vm.push(value)
+		case OpDefineGlobal:
+			vm.globals[readConstant().(string)] = vm.pop()
+		case OpSetGlobal:
+			name := readConstant().(string)
+			if _, ok := vm.globals[name]; !ok {
+				vm.error(frame, fmt.Sprintf("Undefined variable %s", name))
+			}
+			vm.globals[name] = vm.pop()
+		case OpEqual:
+			right, left := vm.pop(), vm.pop()
+			vm.push(interpreter.IsEqual(left, right))
+		case OpNotEqual:
+			right, left := vm.pop(), vm.pop()
+			vm.push(!interpreter.IsEqual(left, right))
+		case OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpSubtract, OpMultiply, OpDivide:
+			right, left := vm.pop(), vm.pop()
+			a, leftIsNumber := left.(float64)
+			b, rightIsNumber := right.(float64)
+			if !leftIsNumber || !rightIsNumber {
+				vm.error(frame, "operands must be numbers")
+			}
+			vm.push(arithmetic(op, a, b))
+		case OpAdd:
+			right, left := vm.pop(), vm.pop()
+			switch a := left.(type) {
+			case float64:
+				if b, ok := right.(float64); ok {
+					vm.push(a + b)
+					continue
+				}
+			case string:
+				if b, ok := right.(string); ok {
+					vm.push(a + b)
+					continue
+				}
+			}
+			vm.error(frame, "operands must be either numbers or strings")
+		case OpNot:
+			vm.push(!interpreter.IsTruthy(vm.pop()))
+		case OpNegate:
+			value, ok := vm.pop().(float64)
+			if !ok {
+				vm.error(frame, "operand must be a number")
+			}
+			vm.push(-value)
+		case OpPrint:
+			io.WriteString(vm.stdOut, interpreter.Stringify(vm.pop())+"\n")
+		case OpJump:
+			offset := readUint16()
+			frame.ip += offset
+		case OpJumpIfFalse:
+			offset := readUint16()
+			if !interpreter.IsTruthy(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case OpLoop:
+			offset := readUint16()
+			frame.ip -= offset
+			vm.checkInterrupted()
+		case OpCall:
+			argCount := int(readByte())
+			vm.callValue(frame, argCount, nil)
+			frame = &vm.frames[len(vm.frames)-1]
+			code = frame.closure.Function.Chunk.Code
+		case OpCallNamed:
+			argCount, named := int(readByte()), int(readByte())
+			names := make([]string, argCount)
+			for i := argCount - named; i < argCount; i++ {
+				names[i] = readConstant().(string)
+			}
+			vm.callValue(frame, argCount, names)
+			frame = &vm.frames[len(vm.frames)-1]
+			code = frame.closure.Function.Chunk.Code
+		case OpDefault:
+			slot, offset := int(readByte()), readUint16()
+			if vm.stack[frame.base+slot] != interpreter.Missing {
+				frame.ip += offset
+			}
+		case OpClosure:
+			function := readConstant().(*Function)
+			closure := &Closure{Function: function, Upvalues: make([]*Upvalue, function.UpvalueCount)}
+			for i := range closure.Upvalues {
+				isLocal, index := readByte(), int(readByte())
+				if isLocal == 1 {
+					closure.Upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.Upvalues[i] = frame.closure.Upvalues[index]
+				}
+			}
+			vm.push(closure)
+		case OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:frame.base]
+			if len(vm.frames) == 0 {
+				return
+			}
+			vm.push(result)
+			frame = &vm.frames[len(vm.frames)-1]
+			code = frame.closure.Function.Chunk.Code
+		case OpList:
+			count := readUint16()
+			elements := make([]any, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(interpreter.NewList(elements))
+		case OpTemplate:
+			count := readUint16()
+			var result strings.Builder
+			for _, part := range vm.stack[len(vm.stack)-count:] {
+				result.WriteString(interpreter.Stringify(part))
+			}
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(result.String())
+		case OpMap:
+			count := readUint16()
+			entries := vm.stack[len(vm.stack)-2*count:]
+			result := interpreter.NewMap()
+			for i := 0; i < len(entries); i += 2 {
+				if err := interpreter.CheckKey(entries[i]); err != nil {
+					vm.error(frame, err.Error())
+				}
+				result.Set(entries[i], entries[i+1])
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*count]
+			vm.push(result)
+		case OpIndex:
+			index, object := vm.pop(), vm.pop()
+			value, err := interpreter.Index(object, index)
+			if err != nil {
+				vm.error(frame, err.Error())
+			}
+			vm.push(value)
+		case OpSetIndex:
+			value, index, object := vm.pop(), vm.pop(), vm.pop()
+			if err := interpreter.SetIndex(object, index, value); err != nil {
+				vm.error(frame, err.Error())
+			}
+		case OpClass:
+			vm.push(&Class{Name: readConstant().(string), Methods: make(map[string]*Closure)})
+		case OpMethod:
+			method := vm.pop().(*Closure)
+			vm.peek(0).(*Class).Methods[readConstant().(string)] = method
+		case OpGetProperty:
+			value, err := interpreter.GetProperty(vm.pop(), readConstant().(string), func(instance *interpreter.Instance, name string) (any, bool) {
+				method, ok := instance.Class.(*Class).Methods[name]
+				if !ok {
+					return nil, false
+				}
+				return &BoundMethod{Receiver: instance, Method: method}, true
+			})
+			if err != nil {
+				vm.error(frame, err.Error())
+			}
+			vm.push(value)
+		case OpTry:
+			offset := readUint16()
+			vm.handlers = append(vm.handlers, handler{frames: len(vm.frames), stack: len(vm.stack), ip: frame.ip + offset})
+		case OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case OpCatch:
+			vm.push(interpreter.Catch(vm.pop().(errors.RuntimeError)))
+		case OpThrow:
+			value := vm.pop()
+			if err, ok := value.(errors.RuntimeError); ok {
+				// an error the handler of a coba block is done with
+				panic(err)
+			}
+			token := frame.closure.Function.Chunk.TokenAt(frame.ip - 1)
+			panic(interpreter.ToRuntimeError(token, interpreter.Throw(value)))
+		case OpInherit:
+			superclass, ok := vm.peek(1).(*Class)
+			if !ok {
+				vm.error(frame, "induk kelas must be a kelas")
+			}
+			// methods are copied down before the kelas declares its own, which
+			// then replace the inherited ones
+			for name, method := range superclass.Methods {
+				vm.peek(0).(*Class).Methods[name] = method
+			}
+			vm.pop()
+		case OpGetSuper:
+			name := readConstant().(string)
+			superclass, instance := vm.pop().(*Class), vm.pop().(*interpreter.Instance)
+			method, ok := superclass.Methods[name]
+			if !ok {
+				vm.error(frame, fmt.Sprintf("Undefined property %s", name))
+			}
+			vm.push(&BoundMethod{Receiver: instance, Method: method})
+		case OpSetProperty:
+			value, object := vm.pop(), vm.pop()
+			if err := interpreter.SetProperty(object, readConstant().(string), value); err != nil {
+				vm.error(frame, err.Error())
+			}
+		case OpIterate:
+			iterator, err := interpreter.NewIterator(vm.pop())
+			if err != nil {
+				vm.error(frame, err.Error())
+			}
+			vm.push(iterator)
+		case OpForNext:
+			offset := readUint16()
+			element, ok := vm.peek(0).(*interpreter.Iterator).Next()
+			if ok {
+				vm.push(element)
+			} else {
+				frame.ip += offset
+			}
+		default:
+			panic(fmt.Sprintf("vm: unknown opcode %d", op))
+		}
+	}
+}
+
+func arithmetic(op OpCode, a, b float64) any {
+	switch op {
+	case OpGreater:
+		return a > b
+	case OpGreaterEqual:
+		return a >= b
+	case OpLess:
+		return a < b
+	case OpLessEqual:
+		return a <= b
+	case OpSubtract:
+		return a - b
+	case OpMultiply:
+		return a * b
+	case OpDivide:
+		return a / b
+	}
+	return nil
+}
+
+// callValue calls the callee sitting below its argCount arguments on the
+// stack, names holds the argument names of a call with named arguments.
+// Closures get a new frame, natives run right away and leave their result in
+// place of the callee.
+func (vm *VM) callValue(frame *callFrame, argCount int, names []string) {
+	callee := vm.peek(argCount)
+	token := frame.closure.Function.Chunk.TokenAt(frame.ip - 1)
+
+	switch function := callee.(type) {
+	case *Closure:
+		vm.call(function, argCount, names, token)
+		return
+	case *BoundMethod:
+		// the method finds its objek in the slot of the callee
+		vm.stack[len(vm.stack)-argCount-1] = function.Receiver
+		vm.call(function.Method, argCount, names, token)
+		return
+	case *Class:
+		vm.stack[len(vm.stack)-argCount-1] = interpreter.NewInstance(function)
+		if initializer, ok := function.Methods[ast.InitializerName]; ok {
+			vm.call(initializer, argCount, names, token)
+			return
+		}
+		if names != nil {
+			panic(errors.NewRuntimeError(token, fmt.Sprintf("fungsi %s tidak menerima argumen bernama", function.Name)))
+		}
+		if err := interpreter.CheckArity(function.Name, 0, false, argCount); err != nil {
+			panic(errors.NewRuntimeError(token, err.Error()))
+		}
+		return
+	case *interpreter.NativeFunction:
+		if names != nil {
+			panic(errors.NewRuntimeError(token, fmt.Sprintf("fungsi %s tidak menerima argumen bernama", function.Name)))
+		}
+		vm.checkArity(function, argCount, token)
+		arguments := make([]any, argCount)
+		copy(arguments, vm.stack[len(vm.stack)-argCount:])
+		value, err := function.Fn(arguments)
+		if err != nil {
+			panic(interpreter.ToRuntimeError(token, err))
+		}
+		vm.stack = vm.stack[:len(vm.stack)-argCount-1]
+		vm.push(value)
+		return
+	}
+	panic(errors.NewRuntimeError(token, "fungsi call is not callable"))
+}
+
+func (vm *VM) call(closure *Closure, argCount int, names []string, token ast.Token) {
+	signature := closure.Function.Signature
+	if names != nil || signature.Variadic || argCount != len(signature.Parameters) {
+		// the arguments are replaced by one value per parameter
+		bound, err := signature.Bind(vm.stack[len(vm.stack)-argCount:], names)
+		if err != nil {
+			panic(errors.NewRuntimeError(token, err.Error()))
+		}
+		vm.stack = append(vm.stack[:len(vm.stack)-argCount], bound...)
+		argCount = len(bound)
+	}
+	if len(vm.frames) == maxFrames {
+		panic(errors.NewRuntimeError(token, "stack overflow"))
+	}
+	vm.checkInterrupted()
+	vm.frames = append(vm.frames, callFrame{
+		closure: closure,
+		base:    len(vm.stack) - argCount - 1,
+	})
+}
+
+func (vm *VM) checkArity(function *interpreter.NativeFunction, argCount int, token ast.Token) {
+	arity, variadic := function.Arity()
+	if err := interpreter.CheckArity(function.Name, arity, variadic, argCount); err != nil {
+		panic(errors.NewRuntimeError(token, err.Error()))
+	}
+}
+
+// captureUpvalue returns the open upvalue for slot, reusing the one already
+// captured by another closure so both see the same variable.
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var previous *Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		previous = upvalue
+		upvalue = upvalue.next
+	}
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+
+	created := &Upvalue{slot: slot, open: true, next: upvalue}
+	if previous == nil {
+		vm.openUpvalues = created
+	} else {
+		previous.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves every variable at or above slot that was captured off
+// the stack, it is called when those variables go out of scope.
+func (vm *VM) closeUpvalues(slot int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= slot {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.slot]
+		upvalue.open = false
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+func (vm *VM) checkInterrupted() {
+	vm.ticks++
+	if vm.ticks%interruptInterval != 0 {
+		return
+	}
+	if err := vm.ctx.Err(); err != nil {
+		panic(interruption{err: err})
+	}
+}
+
+func (vm *VM) push(value any) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() any {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) peek(distance int) any {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+// stackTrace lists the active frames for an error at token, innermost first.
+func (vm *VM) stackTrace(token ast.Token) []errors.StackFrame {
+	trace := make([]errors.StackFrame, 0, len(vm.frames))
+	line := token.LineNumber
+	for k := len(vm.frames) - 1; k >= 0; k-- {
+		frame := vm.frames[k]
+		trace = append(trace, errors.StackFrame{Function: frame.closure.Function.Name, Line: line})
+		if k > 0 {
+			line = vm.frames[k-1].closure.Function.Chunk.TokenAt(vm.frames[k-1].ip - 1).LineNumber
+		}
+	}
+	return trace
+}
+
+// error reports a runtime error at the token the current instruction was
+// compiled from.
+func (vm *VM) error(frame *callFrame, message string) {
+	token := frame.closure.Function.Chunk.TokenAt(frame.ip - 1)
+	panic(errors.NewRuntimeError(token, message))
+}
@@ -0,0 +1,443 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/errors"
+	"github.com/aselhid/indoscript/internal/interpreter"
+	"github.com/aselhid/indoscript/internal/lexer"
+	"github.com/aselhid/indoscript/internal/resolver"
+)
+
+func TestExamplesMatchInterpreter(t *testing.T) {
+	files, err := filepath.Glob("../../example/*.indos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no examples found")
+	}
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			compareBackends(t, string(source))
+		})
+	}
+}
+
+func TestScriptsMatchInterpreter(t *testing.T) {
+	testcases := []string{
+		`cetak 1 + 2 * 3 - 4 / 2;`,
+		"cetak \"a\\tb\\n\\\"c\\\" \\u{1F600}\"; cetak `d\\n\ne`; cetak [\"f\\ng\"];",
+		`misal n = 2; misal f = fungsi (x) => "<${x}>"; cetak "a ${n + 1} ${f(f([n]))} ${"${n}${n}"} \${n}";`,
+		`misal xs = [1]; tambah(xs, xs); cetak xs; cetak xs == xs; misal m = {}; m[1] = m; cetak [m, m == m];`,
+		`cetak "a" + "b"; cetak 1 == 1; cetak 1 != 1; cetak !kosong; cetak -(3);`,
+		`cetak 1 < 2 dan 2 <= 2 atau salah; cetak kosong atau "ya"; cetak 0 dan 1;`,
+		`misal a = 0; selama a < 3 { a = a + 1; } cetak a;`,
+		`jika 1 > 2 { cetak "ya"; } lain { cetak "tidak"; }`,
+		`
+untuk n dalam [3, 2, 1, 0] {
+    jika n == 3 { misal a = "tiga"; cetak a; } lain jika n == 2 { misal b = "dua"; cetak b; }
+    lain jika n == 1 { cetak "satu"; } lain { misal c = fungsi () => n; cetak c(); }
+}
+jika salah { cetak 1; } lain jika kosong { cetak 2; }
+`,
+		`
+fungsi fib(n) {
+    jika n < 2 { balikin n; }
+    balikin fib(n - 1) + fib(n - 2);
+}
+cetak fib(15);
+`,
+		`
+fungsi buat_penghitung() {
+    misal hitungan = 0;
+    fungsi tambah() {
+        hitungan = hitungan + 1;
+        balikin hitungan;
+    }
+    balikin tambah;
+}
+misal pertama = buat_penghitung();
+misal kedua = buat_penghitung();
+cetak pertama(); cetak pertama(); cetak kedua(); cetak pertama;
+`,
+		`
+misal fungsi_fungsi = [];
+misal i = 0;
+selama i < 3 {
+    misal salinan = i;
+    fungsi tampilkan() { cetak salinan; }
+    tambah(fungsi_fungsi, tampilkan);
+    i = i + 1;
+}
+fungsi_fungsi[0](); fungsi_fungsi[1](); fungsi_fungsi[2]();
+`,
+		`
+fungsi luar() {
+    misal x = "luar";
+    fungsi tengah() {
+        fungsi terdalam() { x = x + "!"; balikin x; }
+        balikin terdalam;
+    }
+    balikin tengah();
+}
+misal f = luar();
+cetak f(); cetak f();
+`,
+		`misal xs = [1, [2]]; xs[1][0] = "dua"; cetak xs; cetak panjang(xs); cetak xs == [1, ["dua"]];`,
+		`misal m = {"a": 1}; m["b"] = 2; cetak m; cetak kunci(m); cetak hapus(m, "a"); cetak m["b"];`,
+		`cetak 1 + "a";`,
+		`cetak 1 - "a";`,
+		`cetak -"a";`,
+		`cetak tidak_ada;`,
+		`tidak_ada = 1;`,
+		`misal a = 1; a();`,
+		`fungsi f(a) {} f();`,
+		`fungsi f(a) {} f(1, 2);`,
+		`fungsi f(a, ...b) { cetak a; cetak b; } f(1); f(1, 2, 3); f();`,
+		`
+misal n = 10;
+fungsi f(a, b = a + n, c = [b]) { cetak [a, b, c]; }
+f(1); f(1, 2); f(1, c: 3); f(c: 0, a: 5); n = 20; f(1);
+fungsi g(x = 1, ...sisa) { cetak x; cetak sisa; }
+g(); g(2, 3, 4); g(x: 9);
+`,
+		`
+misal n = 3;
+misal tambah_n = fungsi (x) { balikin x + n; };
+fungsi buat(k) { balikin fungsi (x) => x * k; }
+cetak tambah_n(1); cetak buat(2)(5); cetak tambah_n; cetak (fungsi (...xs) => xs)(1, 2);
+`,
+		`misal f = fungsi (x) => x; f();`,
+		`fungsi f(a, b = 1) {} f(1, 2, 3);`,
+		`fungsi f(a, b = 1) {} f(c: 1);`,
+		`fungsi f(a, b = 1) {} f(1, a: 2);`,
+		`fungsi f(a, b = 1) {} f(b: 2);`,
+		`panjang(teks: "a");`,
+		`
+fungsi buat(...awal) {
+    fungsi tambah(...lagi) { balikin panjang(awal) + panjang(lagi); }
+    balikin tambah;
+}
+cetak buat(1, 2)(3);
+`,
+		`panjang(1);`,
+		`
+fungsi buat() {
+    misal fs = [];
+    untuk misal i = 0; i < 3; i = i + 1 {
+        misal k = i * 2;
+        tambah(fs, fungsi () => i + k);
+    }
+    untuk x dalam [10, 20] { tambah(fs, fungsi () => x); }
+    balikin fs;
+}
+untuk f dalam buat() { cetak f(); }
+misal n = 0;
+untuk n = 5; n < 7; n = n + 1 { cetak n; }
+cetak n;
+untuk misal a = 0; a < 2; a = a + 1 { untuk misal b = 0; b < 2; b = b + 1 { cetak a * 10 + b; } }
+untuk c dalam "añ" { cetak c; }
+untuk k dalam {"x": 1, "y": 2} { cetak k; }
+untuk x dalam rentang(0, 1, 0.25) { cetak x; }
+`,
+		"misal i = 0;\nuntuk x dalam kosong {}",
+		`
+misal fs = [];
+luar: selama benar {
+    misal x = panjang(fs);
+    untuk misal i = 0; i < 5; i = i + 1 {
+        jika i == 1 { lanjut; }
+        jika panjang(fs) > 4 { berhenti luar; }
+        jika i == 3 { lanjut luar; }
+        misal y = i;
+        tambah(fs, fungsi () => [x, y, i]);
+    }
+}
+untuk f dalam fs { cetak f(); }
+misal n = 0;
+selama n < 10 { n = n + 1; jika n == 2 { lanjut; } jika n > 6 { berhenti; } cetak n; }
+baris: untuk x dalam "abc" { untuk y dalam rentang(3) { jika y > 1 { lanjut baris; } cetak x + "!"; } }
+fungsi f() { untuk x dalam [1, 2, 3] { jika x == 2 { balikin x; } } }
+cetak f();
+`,
+		`
+misal fs = [];
+luar: selama benar {
+    misal x = panjang(fs);
+    selama benar {
+        jika panjang(fs) == 2 { berhenti luar; }
+        tambah(fs, fungsi () => x);
+    }
+}
+fungsi timpa(a, b, c) { balikin a + b + c; }
+cetak timpa(7, 8, 9);
+cetak fs[0]();
+`,
+		"misal xs = [1];\ncetak xs[3];",
+		"misal xs = [1];\nxs[-1] = 2;",
+		`misal m = {[1]: 2};`,
+		"misal m = {};\ncetak m[\"x\"];",
+		`
+kelas Akun {
+    inisialisasi(nama, saldo = 0) {
+        ini.nama = nama;
+        ini.saldo = saldo;
+        jika saldo < 0 { balikin; }
+        ini.aktif = benar;
+    }
+    setor(n) { ini.saldo = ini.saldo + n; balikin ini; }
+    laporan() {
+        misal f = fungsi () => [ini.nama, ini.saldo];
+        balikin f;
+    }
+}
+misal a = Akun("budi", saldo: 10);
+misal setor = a.setor;
+setor(5).setor(1);
+cetak a;
+cetak a.laporan()();
+cetak Akun("utang", -1);
+cetak [Akun, setor, a.inisialisasi("ani")];
+cetak panjang(fungsi () => 1);
+`,
+		"kelas A { inisialisasi(a) {} }\nA();",
+		"kelas A {}\nA(1, 2);",
+		"kelas A {}\ncetak A().x;",
+		"misal xs = [];\nxs.x = 1;",
+		`
+kelas A {
+    inisialisasi(x) { ini.x = x; }
+    nama() { balikin "A"; }
+    salam() { balikin "halo dari " + ini.nama(); }
+}
+kelas B < A {
+    nama() { balikin "B/" + induk.nama(); }
+}
+kelas C < B {
+    inisialisasi() { induk.inisialisasi(3); }
+    salam() {
+        misal f = fungsi () => induk.salam;
+        balikin f()() + "?";
+    }
+}
+cetak B(1).salam();
+cetak C().salam();
+cetak C();
+{
+    kelas D < C { nama() { balikin "D"; } }
+    cetak D().salam();
+}
+B();
+`,
+		"misal A = [];\nkelas B < A {}",
+		"kelas A {}\nkelas B < A { f() { balikin induk.f; } }\nB().f();",
+		`
+fungsi bagi(a, b) {
+    jika b == 0 { lempar "nol"; }
+    balikin a / b;
+}
+coba { cetak bagi(1, 0); } tangkap (e) { cetak [e, e.baris, e.jejak]; }
+coba { cetak x; } tangkap (e) { cetak e.pesan; } akhirnya { cetak "akhirnya"; }
+fungsi f() { coba { balikin "coba"; } akhirnya { cetak "f"; } }
+cetak f();
+fungsi g() { coba { balikin 1; } akhirnya { balikin 2; } }
+cetak g();
+misal fs = [];
+luar: untuk i dalam [1, 2] {
+    untuk j dalam [1, 2] {
+        coba {
+            misal k = [i, j];
+            tambah(fs, fungsi () => k);
+            coba { jika j == 2 { lanjut luar; } } akhirnya { cetak "dalam"; }
+        } akhirnya { cetak "luar"; }
+        cetak [i, j];
+    }
+}
+cetak fs[3]();
+coba {
+    coba { lempar 3; } tangkap (e) { tambah(fs, fungsi () => e); lempar 4; } akhirnya { cetak "ulang"; }
+} tangkap (e) { cetak [e.nilai, fs[4]().nilai]; }
+fungsi rekursi(n) { balikin rekursi(n + 1); }
+coba { rekursi(0); } tangkap (e) { cetak e.pesan; }
+kelas K { inisialisasi() { coba { lempar "x"; } tangkap (e) { ini.e = e.pesan; balikin; } } }
+cetak K();
+`,
+		"fungsi f() { coba { cetak x; } akhirnya { cetak 1; } }\nf();",
+		"fungsi f() { coba { lempar 1; } tangkap (e) { lempar e; } }\nf();",
+	}
+	for _, testcase := range testcases {
+		compareBackends(t, testcase)
+	}
+}
+
+func TestGlobalsDefinedByHost(t *testing.T) {
+	stmts := parse(t, `cetak sapa("dunia");`)
+	function, err := Compile(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdOut := new(strings.Builder)
+	vm := NewVM(stdOut)
+	vm.Define("sapa", interpreter.NewNativeFunction("sapa", 1, func(args []any) (any, error) {
+		return "halo " + args[0].(string), nil
+	}))
+	if err := vm.Run(context.Background(), function); err != nil {
+		t.Fatal(err)
+	}
+	if stdOut.String() != "halo dunia\n" {
+		t.Fatalf("unexpected output %q", stdOut.String())
+	}
+}
+
+func TestRunIsCancelled(t *testing.T) {
+	function, err := Compile(parse(t, `selama benar {}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := NewVM(io.Discard).Run(ctx, function); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	function, err := Compile(parse(t, `fungsi f() { balikin f(); } f();`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewVM(io.Discard).Run(context.Background(), function)
+	if err == nil || !strings.Contains(err.Error(), "stack overflow") {
+		t.Fatalf("expected stack overflow, got %v", err)
+	}
+}
+
+func TestTooManyLocals(t *testing.T) {
+	var source strings.Builder
+	source.WriteString("fungsi f() {\n")
+	for i := 0; i < maxLocals; i++ {
+		fmt.Fprintf(&source, "misal v%d = %d;\n", i, i)
+	}
+	source.WriteString("}\n")
+
+	_, err := Compile(parse(t, source.String()))
+	if err == nil || !strings.Contains(err.Error(), "too many local variables") {
+		t.Fatalf("expected too many locals error, got %v", err)
+	}
+}
+
+func compareBackends(t *testing.T, source string) {
+	t.Helper()
+	stmts := parse(t, source)
+
+	treeOut := new(strings.Builder)
+	treeErr := interpreter.NewInterpreter(treeOut, io.Discard).Interpret(stmts)
+
+	function, err := Compile(stmts)
+	if err != nil {
+		t.Fatalf("failed to compile %q: %v", source, err)
+	}
+	vmOut := new(strings.Builder)
+	vmErr := NewVM(vmOut).Run(context.Background(), function)
+
+	if treeOut.String() != vmOut.String() {
+		t.Fatalf("output of %q differs\ntree: %q\nvm:   %q", source, treeOut.String(), vmOut.String())
+	}
+	if describe(treeErr) != describe(vmErr) {
+		t.Fatalf("error of %q differs\ntree: %s\nvm:   %s", source, describe(treeErr), describe(vmErr))
+	}
+}
+
+func describe(err error) string {
+	if e, ok := err.(errors.RuntimeError); ok {
+		return fmt.Sprintf("[line %d] %s", e.Token().LineNumber, e.Error())
+	}
+	return fmt.Sprint(err)
+}
+
+func parse(t testing.TB, source string) []ast.Stmt {
+	t.Helper()
+	tokens := lexer.NewScanner(strings.NewReader(source), io.Discard).ScanTokens()
+	stmts, hasError := ast.NewParser(tokens, io.Discard).Parse()
+	if hasError {
+		t.Fatalf("failed to parse %q", source)
+	}
+	if hasError := resolver.NewResolver(io.Discard).Resolve(stmts); hasError {
+		t.Fatalf("failed to resolve %q", source)
+	}
+	return stmts
+}
+
+const fibSource = `
+fungsi fib(n) {
+    jika n < 2 { balikin n; }
+    balikin fib(n - 1) + fib(n - 2);
+}
+fib(20);
+`
+
+const factorialSource = `
+fungsi faktorial(n) {
+    jika n < 2 { balikin 1; }
+    balikin n * faktorial(n - 1);
+}
+misal i = 0;
+selama i < 200 {
+    faktorial(50);
+    i = i + 1;
+}
+`
+
+func BenchmarkFibTree(b *testing.B) {
+	benchmarkTree(b, fibSource)
+}
+
+func BenchmarkFibVM(b *testing.B) {
+	benchmarkVM(b, fibSource)
+}
+
+func BenchmarkFactorialTree(b *testing.B) {
+	benchmarkTree(b, factorialSource)
+}
+
+func BenchmarkFactorialVM(b *testing.B) {
+	benchmarkVM(b, factorialSource)
+}
+
+func benchmarkTree(b *testing.B, source string) {
+	stmts := parse(b, source)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := interpreter.NewInterpreter(io.Discard, io.Discard).Interpret(stmts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkVM(b *testing.B, source string) {
+	function, err := Compile(parse(b, source))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := NewVM(io.Discard).Run(context.Background(), function); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,45 +1,119 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"io"
 	"os"
 
-	"github.com/aselhid/indoscript/internal/ast"
-	"github.com/aselhid/indoscript/internal/interpreter"
-	"github.com/aselhid/indoscript/internal/lexer"
+	"github.com/aselhid/indoscript/internal/dap"
+	"github.com/aselhid/indoscript/internal/lsp"
+	"github.com/aselhid/indoscript/pkg/indoscript"
 )
 
+var backends = map[string]indoscript.Backend{
+	"tree": indoscript.BackendTree,
+	"vm":   indoscript.BackendVM,
+}
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: indoscript [script].indos")
-		os.Exit(1)
+	backendName := flag.String("backend", "tree", "how scripts are executed, either tree or vm")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: indoscript")
+		fmt.Fprintln(flag.CommandLine.Output(), "       indoscript [-backend tree|vm] [script].indos")
+		fmt.Fprintln(flag.CommandLine.Output(), "       indoscript check [script].indos...")
+		fmt.Fprintln(flag.CommandLine.Output(), "       indoscript fmt [-w] [-d] [script].indos...")
+		fmt.Fprintln(flag.CommandLine.Output(), "       indoscript lsp")
+		fmt.Fprintln(flag.CommandLine.Output(), "       indoscript dap")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	backend, ok := backends[*backendName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown backend %s, expected tree or vm\n", *backendName)
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if flag.NArg() == 0 {
+		// a session keeps its globals between inputs, which only the tree
+		// backend supports
+		if backend != indoscript.BackendTree {
+			fmt.Fprintln(os.Stderr, "the REPL only runs on the tree backend")
+			os.Exit(exitUsage)
+		}
+		os.Exit(repl(os.Stdin, os.Stdout, os.Stderr))
+	}
+	if flag.Arg(0) == "check" {
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(exitUsage)
+		}
+		os.Exit(check(flag.Args()[1:]))
+	}
+	if flag.Arg(0) == "fmt" {
+		os.Exit(formatFiles(flag.Args()[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if flag.Arg(0) == "dap" {
+		if err := dap.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if flag.Arg(0) == "lsp" {
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
 
-	filename := os.Args[1]
-	if err := runFile(filename); err != nil {
-		reportError(err)
-		os.Exit(1) // TODO: return exit code based on err
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	filename := flag.Arg(0)
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitIOErr)
+	}
+	if err := run(string(source), backend); err != nil {
+		reportError(filename, string(source), err)
+		os.Exit(exitCode(err))
 	}
 }
 
-func runFile(filename string) error {
-	file, err := os.Open(filename)
+func run(source string, backend indoscript.Backend) error {
+	program, err := indoscript.Compile(source)
 	if err != nil {
 		return err
 	}
-	run(file)
-	return nil
+	return program.Run(context.Background(), indoscript.Options{
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+		Backend: backend,
+	})
 }
 
-func run(reader io.Reader) {
-	scanner := lexer.NewScanner(reader, os.Stderr)
-	tokens := scanner.ScanTokens()
-	parser := ast.NewParser(tokens)
-	stmts, hasError := parser.Parse()
-	if hasError {
-		os.Exit(1)
+// check compiles every file without running it and reports all the errors
+// found, it returns the exit code of the last file that failed.
+func check(filenames []string) int {
+	code := 0
+	for _, filename := range filenames {
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = exitIOErr
+			continue
+		}
+		if _, err := indoscript.Compile(string(source)); err != nil {
+			reportError(filename, string(source), err)
+			code = exitCode(err)
+		}
 	}
-	interpreter := interpreter.NewInterpreter(os.Stdout, os.Stderr)
-	interpreter.Interpret(stmts)
+	return code
 }
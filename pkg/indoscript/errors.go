@@ -0,0 +1,170 @@
+package indoscript
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aselhid/indoscript/internal/diagnostic"
+	"github.com/aselhid/indoscript/internal/errors"
+)
+
+// Kind tells in which phase an Error was reported.
+type Kind uint8
+
+const (
+	// KindSyntax errors are found while compiling, before the script runs.
+	KindSyntax Kind = iota
+	// KindRuntime errors stop a running script.
+	KindRuntime
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindSyntax:
+		return "syntax error"
+	case KindRuntime:
+		return "runtime error"
+	}
+	return "unknown error"
+}
+
+// Diagnostic is the structured form of an error tied to the source, it
+// carries the span of source the error points at.
+type Diagnostic = diagnostic.Diagnostic
+
+// StackFrame is a call that was in progress when a runtime error happened.
+// Function is empty for the top level of the script, Line is the line the
+// frame was at.
+type StackFrame = errors.StackFrame
+
+// Error is a single error reported by indoscript.
+type Error struct {
+	Kind Kind
+	// Line and Column are the 1-based position the error was reported at,
+	// both are 0 when it is not tied to the source. Column counts runes.
+	Line    int
+	Column  int
+	Message string
+	// Stack holds the calls that led to a runtime error, the innermost call
+	// first and the top level of the script last.
+	Stack []StackFrame
+
+	diagnostic *Diagnostic
+	err        error
+}
+
+func (e *Error) Error() string {
+	if e.Kind == KindRuntime && e.Line > 0 {
+		return fmt.Sprintf("[line %d] Runtime error: %s", e.Line, e.err.Error())
+	}
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Diagnostic returns the structured form of the error, ok is false when the
+// error is not tied to the source.
+func (e *Error) Diagnostic() (d Diagnostic, ok bool) {
+	if e.diagnostic == nil {
+		return Diagnostic{}, false
+	}
+	return *e.diagnostic, true
+}
+
+// ErrorList is returned by Compile, it holds every error found in the source.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, err := range l {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// PrintError writes err to w. Errors tied to the source are rendered with the
+// line of source they point at, filename is only used to label them.
+func PrintError(w io.Writer, filename, source string, err error) {
+	printer := diagnostic.NewPrinter(w, filename, source)
+	var list ErrorList
+	var scriptErr *Error
+	switch {
+	case stderrors.As(err, &list):
+		for _, e := range list {
+			printError(w, printer, filename, e)
+		}
+	case stderrors.As(err, &scriptErr):
+		printError(w, printer, filename, scriptErr)
+	default:
+		fmt.Fprintln(w, err)
+	}
+}
+
+func printError(w io.Writer, printer *diagnostic.Printer, filename string, err *Error) {
+	if d, ok := err.Diagnostic(); ok {
+		printer.Print(d)
+	} else {
+		fmt.Fprintf(w, "error: %s\n", err.Message)
+	}
+	if len(err.Stack) > 1 {
+		printStack(w, filename, err.Stack)
+	}
+}
+
+// printStack writes the traceback of a runtime error raised inside a fungsi,
+// runs of the same frame, as left by deep recursion, are written once.
+func printStack(w io.Writer, filename string, stack []StackFrame) {
+	fmt.Fprintln(w, "stack trace, most recent call first:")
+	for i := 0; i < len(stack); {
+		frame, repeated := stack[i], 1
+		for i+repeated < len(stack) && stack[i+repeated] == frame {
+			repeated++
+		}
+		i += repeated
+
+		name := frame.Function
+		if name == "" {
+			name = "<skrip>"
+		}
+		fmt.Fprintf(w, "    %s at %s:%d", name, filename, frame.Line)
+		if repeated > 1 {
+			fmt.Fprintf(w, " (repeated %d times)", repeated)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func newError(kind Kind, err error) *Error {
+	var d diagnostic.Diagnostic
+	var stack []StackFrame
+	switch e := err.(type) {
+	case diagnostic.Diagnostic:
+		d = e
+	case errors.RuntimeError:
+		d = e.Diagnostic()
+		stack = e.Trace()
+	default:
+		return &Error{Kind: kind, Message: err.Error(), err: err}
+	}
+	return &Error{
+		Kind:       kind,
+		Line:       d.Span.Start.Line,
+		Column:     d.Span.Start.Column,
+		Message:    d.Message,
+		Stack:      stack,
+		diagnostic: &d,
+		err:        err,
+	}
+}
+
+func newErrorList(kind Kind, errs []error) ErrorList {
+	list := make(ErrorList, len(errs))
+	for i, err := range errs {
+		list[i] = newError(kind, err)
+	}
+	return list
+}
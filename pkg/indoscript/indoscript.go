@@ -0,0 +1,201 @@
+// Package indoscript embeds the indoscript language in Go programs.
+//
+// A script is compiled once with Compile and can then be run any number of
+// times, each run getting its own fresh global environment:
+//
+//	program, err := indoscript.Compile(`cetak "halo " + nama;`)
+//	if err != nil {
+//		return err
+//	}
+//	err = program.Run(ctx, indoscript.Options{
+//		Stdout:  os.Stdout,
+//		Globals: map[string]any{"nama": "dunia"},
+//	})
+package indoscript
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/format"
+	"github.com/aselhid/indoscript/internal/interpreter"
+	"github.com/aselhid/indoscript/internal/lexer"
+	"github.com/aselhid/indoscript/internal/resolver"
+	"github.com/aselhid/indoscript/internal/vm"
+)
+
+// Program is a scanned, parsed and resolved script ready to be run.
+type Program struct {
+	stmts []ast.Stmt
+
+	// bytecode is compiled the first time the program runs on BackendVM
+	compileOnce sync.Once
+	bytecode    *vm.Function
+	compileErr  error
+}
+
+// Backend selects how a Program is executed.
+type Backend uint8
+
+const (
+	// BackendTree walks the syntax tree directly, it is the default.
+	BackendTree Backend = iota
+	// BackendVM compiles the program to bytecode and runs it on a stack
+	// machine, which is faster for long running scripts.
+	BackendVM
+)
+
+func (b Backend) String() string {
+	switch b {
+	case BackendTree:
+		return "tree"
+	case BackendVM:
+		return "vm"
+	}
+	return "unknown"
+}
+
+// Options configures a single run of a Program.
+type Options struct {
+	// Stdout receives everything printed with cetak. Output is discarded when
+	// it is nil.
+	Stdout io.Writer
+	// Stderr is handed to the interpreter for diagnostics. Output is
+	// discarded when it is nil.
+	Stderr io.Writer
+	// Globals are defined in the global environment before the script runs.
+	// Supported values are nil, booleans, strings, Go numeric types,
+	// *NativeFunction, *List, *Map, *Exception and []any or map[string]any
+	// holding supported values. Keys of a map[string]any are inserted in
+	// sorted order.
+	Globals map[string]any
+	// Backend runs the program, both backends print the same output and
+	// report the same errors.
+	Backend Backend
+}
+
+// Compile scans, parses and resolves src. When the source is invalid the
+// returned error is an ErrorList holding every error that was reported.
+func Compile(src string) (*Program, error) {
+	stmts, err := compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{stmts: stmts}, nil
+}
+
+func compile(src string) ([]ast.Stmt, error) {
+	scanner := lexer.NewScanner(strings.NewReader(src), io.Discard)
+	stmts, err := parse(scanner, scanner.ScanTokens())
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := resolver.NewResolver(io.Discard)
+	if hasError := resolver.Resolve(stmts); hasError {
+		return nil, newErrorList(KindSyntax, resolver.Errors())
+	}
+	return stmts, nil
+}
+
+// parse parses tokens scanned by scanner, the tokens around a scanner error
+// are still parsed so both kinds of errors are reported together.
+func parse(scanner *lexer.Scanner, tokens []ast.Token) ([]ast.Stmt, error) {
+	parser := ast.NewParser(tokens, io.Discard)
+	stmts, hasError := parser.Parse()
+	if errs := append(scanner.Errors(), parser.Errors()...); len(errs) > 0 || hasError {
+		list := newErrorList(KindSyntax, errs)
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].Line != list[j].Line {
+				return list[i].Line < list[j].Line
+			}
+			return list[i].Column < list[j].Column
+		})
+		return nil, list
+	}
+	return stmts, nil
+}
+
+// Format returns src in the canonical style, keeping its comments. Only
+// syntax is checked, src may use undefined variables. Invalid source is
+// returned as an ErrorList.
+func Format(src string) (string, error) {
+	scanner := lexer.NewScanner(strings.NewReader(src), io.Discard)
+	tokens := scanner.ScanTokensWithComments()
+	stmts, err := parse(scanner, tokens)
+	if err != nil {
+		return "", err
+	}
+	return format.Source(tokens, stmts), nil
+}
+
+// Run executes the program until it finishes, fails or ctx is done. A failing
+// script returns an *Error of KindRuntime, a cancelled one returns ctx.Err().
+func (p *Program) Run(ctx context.Context, opts Options) error {
+	stdOut, stdErr := opts.Stdout, opts.Stderr
+	if stdOut == nil {
+		stdOut = io.Discard
+	}
+	if stdErr == nil {
+		stdErr = io.Discard
+	}
+
+	globals, err := convertGlobals(opts.Globals)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Backend {
+	case BackendTree:
+		interpreter := interpreter.NewInterpreter(stdOut, stdErr)
+		for name, value := range globals {
+			interpreter.Define(name, value)
+		}
+		err = interpreter.InterpretContext(ctx, p.stmts)
+	case BackendVM:
+		p.compileOnce.Do(func() {
+			p.bytecode, p.compileErr = vm.Compile(p.stmts)
+		})
+		if p.compileErr != nil {
+			return ErrorList{newError(KindSyntax, p.compileErr)}
+		}
+		machine := vm.NewVM(stdOut)
+		for name, value := range globals {
+			machine.Define(name, value)
+		}
+		err = machine.Run(ctx, p.bytecode)
+	default:
+		return fmt.Errorf("indoscript: unknown backend %d", opts.Backend)
+	}
+
+	return runtimeError(ctx, err)
+}
+
+func convertGlobals(globals map[string]any) (map[string]any, error) {
+	converted := make(map[string]any, len(globals))
+	for name, value := range globals {
+		v, err := toValue(value)
+		if err != nil {
+			return nil, &Error{Kind: KindRuntime, Message: "global " + name + ": " + err.Error(), err: err}
+		}
+		converted[name] = v
+	}
+	return converted, nil
+}
+
+// runtimeError wraps an error returned by a backend, cancellation is returned
+// as is.
+func runtimeError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+		return err
+	}
+	return newError(KindRuntime, err)
+}
@@ -0,0 +1,386 @@
+package indoscript
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	program, err := Compile(`cetak sapaan + ", " + nama; cetak umur + 1; cetak aktif;`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	stdOut := new(strings.Builder)
+	err = program.Run(context.Background(), Options{
+		Stdout: stdOut,
+		Globals: map[string]any{
+			"sapaan": "halo",
+			"nama":   "budi",
+			"umur":   30,
+			"aktif":  true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected run error: %s", err)
+	}
+	if expected := "halo, budi\n31\nbenar\n"; stdOut.String() != expected {
+		t.Fatalf("expected output %q while actual is %q", expected, stdOut.String())
+	}
+}
+
+func TestRunTwiceStartsFresh(t *testing.T) {
+	program, err := Compile(`misal a = 1; cetak a;`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		stdOut := new(strings.Builder)
+		if err := program.Run(context.Background(), Options{Stdout: stdOut}); err != nil {
+			t.Fatalf("unexpected run error: %s", err)
+		}
+		if stdOut.String() != "1\n" {
+			t.Fatalf("expected output %q while actual is %q", "1\n", stdOut.String())
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	_, err := Compile("misal a = 1;\ncetak (a;")
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected ErrorList, got %#v", err)
+	}
+	if len(list) != 1 || list[0].Kind != KindSyntax || list[0].Line != 2 {
+		t.Fatalf("unexpected errors %#v", list)
+	}
+
+	_, err = Compile(`"belum selesai`)
+	if !errors.As(err, &list) || list[0].Message != "unterminated string" {
+		t.Fatalf("expected unterminated string error, got %#v", err)
+	}
+
+	_, err = Compile(`balikin 1;`)
+	if !errors.As(err, &list) || list[0].Kind != KindSyntax || list[0].Line != 1 {
+		t.Fatalf("expected resolver error, got %#v", err)
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	program, err := Compile("misal a = 1;\ncetak a - \"b\";")
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	err = program.Run(context.Background(), Options{})
+	var scriptErr *Error
+	if !errors.As(err, &scriptErr) {
+		t.Fatalf("expected *Error, got %#v", err)
+	}
+	if scriptErr.Kind != KindRuntime || scriptErr.Line != 2 || scriptErr.Message != "operands must be numbers" {
+		t.Fatalf("unexpected error %#v", scriptErr)
+	}
+}
+
+func TestUnsupportedGlobal(t *testing.T) {
+	program, err := Compile(`cetak 1;`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+	err = program.Run(context.Background(), Options{Globals: map[string]any{"x": struct{}{}}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported value") {
+		t.Fatalf("expected unsupported value error, got %v", err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	program, err := Compile(`selama benar { misal a = 1; }`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := program.Run(ctx, Options{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestNativeFunction(t *testing.T) {
+	program, err := Compile(`
+cetak gandakan(21);
+cetak jumlah(1, 2, 3);
+cetak jumlah();
+cetak gandakan;
+`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	stdOut := new(strings.Builder)
+	err = program.Run(context.Background(), Options{
+		Stdout: stdOut,
+		Globals: map[string]any{
+			"gandakan": NewFunction("gandakan", 1, func(arguments []any) (any, error) {
+				return int(arguments[0].(float64)) * 2, nil
+			}),
+			"jumlah": NewVariadicFunction("jumlah", 0, func(arguments []any) (any, error) {
+				total := 0.0
+				for _, argument := range arguments {
+					total += argument.(float64)
+				}
+				return total, nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected run error: %s", err)
+	}
+	if expected := "42\n6\n0\n<fungsi bawaan gandakan>\n"; stdOut.String() != expected {
+		t.Fatalf("expected output %q while actual is %q", expected, stdOut.String())
+	}
+}
+
+func TestNativeFunctionError(t *testing.T) {
+	program, err := Compile("cetak 1;\ngagal();")
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	err = program.Run(context.Background(), Options{
+		Globals: map[string]any{
+			"gagal": NewFunction("gagal", 0, func(arguments []any) (any, error) {
+				return nil, errors.New("koneksi terputus")
+			}),
+		},
+	})
+	var scriptErr *Error
+	if !errors.As(err, &scriptErr) || scriptErr.Line != 2 || scriptErr.Message != "koneksi terputus" {
+		t.Fatalf("expected runtime error from native function, got %#v", err)
+	}
+}
+
+func TestNativeFunctionThrows(t *testing.T) {
+	program, err := Compile(`
+coba { periksa(-1); } tangkap (e) { cetak e.nilai["kode"]; }
+coba { periksa(0); } tangkap (e) { cetak e.pesan; cetak e.baris; }
+coba { periksa(1); } tangkap (e) { cetak e.pesan; }
+cetak periksa(galat("lolos"));
+`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	globals := map[string]any{
+		"periksa": NewFunction("periksa", 1, func(arguments []any) (any, error) {
+			if exception, ok := arguments[0].(*Exception); ok {
+				return exception.Message, nil
+			}
+			switch arguments[0].(float64) {
+			case -1:
+				return nil, Throw(map[string]any{"kode": 7})
+			case 0:
+				return nil, NewException("nol tidak boleh")
+			}
+			return nil, errors.New("koneksi terputus")
+		}),
+	}
+	for _, backend := range []Backend{BackendTree, BackendVM} {
+		stdOut := new(strings.Builder)
+		if err := program.Run(context.Background(), Options{Stdout: stdOut, Globals: globals, Backend: backend}); err != nil {
+			t.Fatalf("%s: unexpected run error: %s", backend, err)
+		}
+		if expected := "7\nnol tidak boleh\n3\nkoneksi terputus\nlolos\n"; stdOut.String() != expected {
+			t.Fatalf("%s: expected output %q while actual is %q", backend, expected, stdOut.String())
+		}
+	}
+}
+
+func TestNativeFunctionThrowsSameException(t *testing.T) {
+	program, err := Compile(`
+coba { gagal(); } tangkap (e) { cetak e.baris; cetak e.jejak; }
+fungsi f() {
+    gagal();
+}
+coba { f(); } tangkap (e) { cetak e.baris; cetak e.jejak; }
+`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	sentinel := NewException("gagal")
+	globals := map[string]any{
+		"gagal": NewFunction("gagal", 0, func(arguments []any) (any, error) {
+			return nil, sentinel
+		}),
+	}
+	expected := "2\n[\"<skrip> baris 2\"]\n4\n[\"f baris 4\", \"<skrip> baris 6\"]\n"
+	for _, backend := range []Backend{BackendTree, BackendVM, BackendTree} {
+		stdOut := new(strings.Builder)
+		if err := program.Run(context.Background(), Options{Stdout: stdOut, Globals: globals, Backend: backend}); err != nil {
+			t.Fatalf("%s: unexpected run error: %s", backend, err)
+		}
+		if stdOut.String() != expected {
+			t.Fatalf("%s: expected output %q while actual is %q", backend, expected, stdOut.String())
+		}
+	}
+	if sentinel.Line != 0 || sentinel.Trace != nil {
+		t.Fatalf("expected the returned galat to stay unraised, got line %d and trace %v", sentinel.Line, sentinel.Trace)
+	}
+}
+
+func TestListGlobal(t *testing.T) {
+	program, err := Compile(`cetak angka; angka[0] = 10;`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	stdOut := new(strings.Builder)
+	err = program.Run(context.Background(), Options{
+		Stdout:  stdOut,
+		Globals: map[string]any{"angka": []any{1, "dua", []any{3.5}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected run error: %s", err)
+	}
+	if expected := "[1, \"dua\", [3.5]]\n"; stdOut.String() != expected {
+		t.Fatalf("expected output %q while actual is %q", expected, stdOut.String())
+	}
+}
+
+func TestMapGlobal(t *testing.T) {
+	program, err := Compile(`cetak konfigurasi; cetak konfigurasi["port"] + 1;`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	stdOut := new(strings.Builder)
+	err = program.Run(context.Background(), Options{
+		Stdout: stdOut,
+		Globals: map[string]any{
+			"konfigurasi": map[string]any{"port": 8080, "host": "localhost", "tag": []any{"a"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected run error: %s", err)
+	}
+	if expected := "{\"host\": \"localhost\", \"port\": 8080, \"tag\": [\"a\"]}\n8081\n"; stdOut.String() != expected {
+		t.Fatalf("expected output %q while actual is %q", expected, stdOut.String())
+	}
+}
+
+func TestBackendVM(t *testing.T) {
+	program, err := Compile("fungsi kali(a) { balikin a * faktor; }\ncetak kali(umur);\ncetak 1 + benar;")
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	for _, backend := range []Backend{BackendTree, BackendVM} {
+		stdOut := new(strings.Builder)
+		err = program.Run(context.Background(), Options{
+			Stdout:  stdOut,
+			Globals: map[string]any{"umur": 21, "faktor": 2},
+			Backend: backend,
+		})
+		if stdOut.String() != "42\n" {
+			t.Fatalf("%s: expected output %q while actual is %q", backend, "42\n", stdOut.String())
+		}
+		var scriptErr *Error
+		if !errors.As(err, &scriptErr) || scriptErr.Kind != KindRuntime || scriptErr.Line != 3 {
+			t.Fatalf("%s: unexpected error %#v", backend, err)
+		}
+	}
+}
+
+func TestPrintError(t *testing.T) {
+	source := "misal a = 1;\ncetak a - \"b\";"
+	program, err := Compile(source)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+	err = program.Run(context.Background(), Options{})
+
+	var scriptErr *Error
+	if !errors.As(err, &scriptErr) || scriptErr.Line != 2 || scriptErr.Column != 9 {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	if d, ok := scriptErr.Diagnostic(); !ok || d.Code != "runtime" {
+		t.Fatalf("unexpected diagnostic %#v", d)
+	}
+
+	out := new(strings.Builder)
+	PrintError(out, "skrip.indos", source, err)
+	expected := "error[runtime]: operands must be numbers\n" +
+		" --> skrip.indos:2:9\n" +
+		"  |\n" +
+		"2 | cetak a - \"b\";\n" +
+		"  |         ^\n"
+	if out.String() != expected {
+		t.Fatalf("expected\n%s\nwhile actual is\n%s", expected, out.String())
+	}
+}
+
+func TestCompileReportsEveryError(t *testing.T) {
+	_, err := Compile("misal a = @;\ncetak 1 +;\n{\n    cetak 2\n}\ncetak \"belum selesai")
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected ErrorList, got %#v", err)
+	}
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{1, "found unexpected character  \"@\""},
+		{1, "expect expression"},
+		{2, "expect expression"},
+		{5, "expect ';' after statement"},
+		{6, "unterminated string"},
+		{6, "expect expression"},
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("expected %d errors while actual is %d: %v", len(expected), len(list), list)
+	}
+	for i, e := range list {
+		if e.Line != expected[i].line || e.Message != expected[i].message {
+			t.Fatalf("expected %q at line %d while actual is %q at line %d", expected[i].message, expected[i].line, e.Message, e.Line)
+		}
+	}
+}
+
+func TestRuntimeErrorStack(t *testing.T) {
+	source := "fungsi terdalam(x) {\n    balikin x - \"a\";\n}\n" +
+		"fungsi luar(x) {\n    balikin terdalam(x);\n}\n" +
+		"luar(1);\n"
+	program, err := Compile(source)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	expected := []StackFrame{{Function: "terdalam", Line: 2}, {Function: "luar", Line: 5}, {Function: "", Line: 7}}
+	for _, backend := range []Backend{BackendTree, BackendVM} {
+		t.Run(backend.String(), func(t *testing.T) {
+			err := program.Run(context.Background(), Options{Backend: backend})
+			var scriptErr *Error
+			if !errors.As(err, &scriptErr) {
+				t.Fatalf("unexpected error %#v", err)
+			}
+			if fmt.Sprint(scriptErr.Stack) != fmt.Sprint(expected) {
+				t.Fatalf("expected stack %v while actual is %v", expected, scriptErr.Stack)
+			}
+
+			out := new(strings.Builder)
+			PrintError(out, "skrip.indos", source, err)
+			expectedTrace := "stack trace, most recent call first:\n" +
+				"    terdalam at skrip.indos:2\n" +
+				"    luar at skrip.indos:5\n" +
+				"    <skrip> at skrip.indos:7\n"
+			if !strings.HasSuffix(out.String(), expectedTrace) {
+				t.Fatalf("expected the output to end with\n%s\nwhile actual is\n%s", expectedTrace, out.String())
+			}
+		})
+	}
+}
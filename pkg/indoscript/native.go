@@ -0,0 +1,58 @@
+package indoscript
+
+import (
+	"github.com/aselhid/indoscript/internal/interpreter"
+)
+
+// NativeFunction is a Go function callable from scripts. Pass it in
+// Options.Globals to make it available under its name.
+type NativeFunction = interpreter.NativeFunction
+
+// Exception is a galat, the error value scripts raise with lempar and catch
+// with tangkap. A native function raises one by returning it as its error.
+type Exception = interpreter.Exception
+
+// NewException creates a galat carrying message, return it as the error of a
+// native function to raise it. Every raise catches a copy, so one galat may be
+// returned any number of times, also from concurrent runs.
+func NewException(message string) *Exception {
+	return interpreter.NewException(message)
+}
+
+// Throw returns the galat raised by 'lempar value', returning it as the error
+// of a native function throws value the way the script would. value may be
+// any value accepted in Options.Globals, the galat describes the problem when
+// it isn't.
+func Throw(value any) *Exception {
+	converted, err := toValue(value)
+	if err != nil {
+		return interpreter.NewException(err.Error())
+	}
+	return interpreter.Throw(converted)
+}
+
+// NewFunction wraps fn as a native function taking exactly arity arguments.
+// Arguments reach fn as nil, bool, float64, string, *List, *Map or *Exception
+// values, and fn may return any value accepted in Options.Globals. A returned
+// error raises a runtime error at the call site, which the script may catch
+// with tangkap. An *Exception is raised as a copy, any other error becomes a
+// galat with its message.
+func NewFunction(name string, arity int, fn func(arguments []any) (any, error)) *NativeFunction {
+	return interpreter.NewNativeFunction(name, arity, convertResult(fn))
+}
+
+// NewVariadicFunction is like NewFunction but fn accepts minArity or more
+// arguments.
+func NewVariadicFunction(name string, minArity int, fn func(arguments []any) (any, error)) *NativeFunction {
+	return interpreter.NewVariadicNativeFunction(name, minArity, convertResult(fn))
+}
+
+func convertResult(fn func(arguments []any) (any, error)) func(arguments []any) (any, error) {
+	return func(arguments []any) (any, error) {
+		value, err := fn(arguments)
+		if err != nil {
+			return nil, err
+		}
+		return toValue(value)
+	}
+}
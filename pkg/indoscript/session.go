@@ -0,0 +1,76 @@
+package indoscript
+
+import (
+	"context"
+	"io"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/interpreter"
+)
+
+// Session runs source piece by piece against one global environment, so
+// variables and functions defined by one call are visible to the next. It is
+// what the REPL is built on and always runs on BackendTree.
+type Session struct {
+	stdOut      io.Writer
+	interpreter *interpreter.Interpreter
+}
+
+// NewSession starts a session, Options.Backend is ignored.
+func NewSession(opts Options) (*Session, error) {
+	stdOut, stdErr := opts.Stdout, opts.Stderr
+	if stdOut == nil {
+		stdOut = io.Discard
+	}
+	if stdErr == nil {
+		stdErr = io.Discard
+	}
+
+	globals, err := convertGlobals(opts.Globals)
+	if err != nil {
+		return nil, err
+	}
+	interpreter := interpreter.NewInterpreter(stdOut, stdErr)
+	for name, value := range globals {
+		interpreter.Define(name, value)
+	}
+	return &Session{stdOut: stdOut, interpreter: interpreter}, nil
+}
+
+// Exec compiles and runs src in the session. It fails with an ErrorList when
+// src does not compile, nothing is run then.
+func (s *Session) Exec(ctx context.Context, src string) error {
+	stmts, err := compile(src)
+	if err != nil {
+		return err
+	}
+	return runtimeError(ctx, s.interpreter.InterpretContext(ctx, stmts))
+}
+
+// Eval is like Exec but also writes the value of every bare expression
+// statement to Stdout, unless the value is kosong. Strings are quoted the way
+// they are inside a list.
+func (s *Session) Eval(ctx context.Context, src string) error {
+	stmts, err := compile(src)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		exprStmt, ok := stmt.(ast.ExprStmt)
+		if !ok {
+			if err := s.interpreter.InterpretContext(ctx, []ast.Stmt{stmt}); err != nil {
+				return runtimeError(ctx, err)
+			}
+			continue
+		}
+
+		value, err := s.interpreter.EvaluateContext(ctx, exprStmt.Expression)
+		if err != nil {
+			return runtimeError(ctx, err)
+		}
+		if value != nil {
+			io.WriteString(s.stdOut, interpreter.Inspect(value)+"\n")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package indoscript
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSessionKeepsState(t *testing.T) {
+	stdOut := new(strings.Builder)
+	session, err := NewSession(Options{Stdout: stdOut, Globals: map[string]any{"awal": 10}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	inputs := []string{
+		`misal a = awal;`,
+		`fungsi tambah(x) { balikin a + x; }`,
+		`tambah(5); "teks"; [1, "dua"]; kosong;`,
+		`a = 1; cetak tambah(1);`,
+	}
+	for _, input := range inputs {
+		if err := session.Eval(context.Background(), input); err != nil {
+			t.Fatalf("unexpected error for %q: %s", input, err)
+		}
+	}
+	if expected := "15\n\"teks\"\n[1, \"dua\"]\n2\n"; stdOut.String() != expected {
+		t.Fatalf("expected output %q while actual is %q", expected, stdOut.String())
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	stdOut := new(strings.Builder)
+	session, err := NewSession(Options{Stdout: stdOut})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var list ErrorList
+	if err := session.Eval(context.Background(), `misal a = ;`); !errors.As(err, &list) {
+		t.Fatalf("expected ErrorList, got %#v", err)
+	}
+	var scriptErr *Error
+	if err := session.Eval(context.Background(), `cetak 1; tidak_ada; cetak 2;`); !errors.As(err, &scriptErr) {
+		t.Fatalf("expected *Error, got %#v", err)
+	}
+	// the session is still usable after an error
+	if err := session.Exec(context.Background(), `misal a = 3; a;`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := session.Eval(context.Background(), `a;`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "1\n3\n"; stdOut.String() != expected {
+		t.Fatalf("expected output %q while actual is %q", expected, stdOut.String())
+	}
+}
@@ -0,0 +1,74 @@
+package indoscript
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aselhid/indoscript/internal/interpreter"
+)
+
+// List is the value of an indoscript list, it is shared by reference between
+// the script and the host.
+type List = interpreter.List
+
+// Map is the value of an indoscript map, its keys keep their insertion order.
+type Map = interpreter.Map
+
+// toValue converts a Go value into its indoscript representation.
+func toValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, bool, string, float64, *NativeFunction, *List, *Map, *Exception:
+		return v, nil
+	case []any:
+		elements := make([]any, len(v))
+		for i, element := range v {
+			converted, err := toValue(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = converted
+		}
+		return interpreter.NewList(elements), nil
+	case map[string]any:
+		// Go maps are unordered, sort the keys so the script sees a
+		// deterministic order
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		result := interpreter.NewMap()
+		for _, key := range keys {
+			converted, err := toValue(v[key])
+			if err != nil {
+				return nil, err
+			}
+			result.Set(key, converted)
+		}
+		return result, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", value)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aselhid/indoscript/internal/ast"
+	"github.com/aselhid/indoscript/internal/diagnostic"
+	"github.com/aselhid/indoscript/internal/lexer"
+	"github.com/aselhid/indoscript/pkg/indoscript"
+)
+
+const (
+	prompt             = "> "
+	continuationPrompt = "... "
+	replFilename       = "<repl>"
+)
+
+// repl reads statements from in until it is exhausted or :keluar is entered.
+// Input with unclosed brackets or raw strings continues on the next line.
+func repl(in io.Reader, out, errOut io.Writer) int {
+	session, err := indoscript.NewSession(indoscript.Options{Stdout: out, Stderr: errOut})
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return exitSoftware
+	}
+
+	fmt.Fprintln(out, "indoscript REPL, type :keluar to exit")
+	scanner := bufio.NewScanner(in)
+	var input strings.Builder
+	for {
+		if input.Len() == 0 {
+			fmt.Fprint(out, prompt)
+		} else {
+			fmt.Fprint(out, continuationPrompt)
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			if err := scanner.Err(); err != nil {
+				fmt.Fprintln(errOut, err)
+				return exitIOErr
+			}
+			return 0
+		}
+		line := scanner.Text()
+
+		if input.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			command := strings.Fields(line)
+			switch command[0] {
+			case ":keluar":
+				return 0
+			case ":reset":
+				reset, err := indoscript.NewSession(indoscript.Options{Stdout: out, Stderr: errOut})
+				if err != nil {
+					fmt.Fprintln(errOut, err)
+					return exitSoftware
+				}
+				session = reset
+			case ":muat":
+				if len(command) != 2 {
+					fmt.Fprintln(errOut, "usage: :muat [script].indos")
+					continue
+				}
+				load(session, command[1], errOut)
+			default:
+				fmt.Fprintf(errOut, "unknown command %s, available commands are :keluar, :reset and :muat\n", command[0])
+			}
+			continue
+		}
+
+		input.WriteString(line)
+		input.WriteByte('\n')
+		if isIncomplete(input.String()) {
+			continue
+		}
+
+		source := strings.TrimSpace(input.String())
+		input.Reset()
+		if source == "" {
+			continue
+		}
+		source = withSemicolon(source)
+		if err := session.Eval(context.Background(), source); err != nil {
+			indoscript.PrintError(errOut, replFilename, source, err)
+		}
+	}
+}
+
+func load(session *indoscript.Session, filename string, errOut io.Writer) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return
+	}
+	if err := session.Exec(context.Background(), string(source)); err != nil {
+		indoscript.PrintError(errOut, filename, string(source), err)
+	}
+}
+
+// withSemicolon adds the ';' a single statement may be entered without. It
+// goes on a line of its own, after any trailing comment, and only when source
+// doesn't parse as it is but does with it.
+func withSemicolon(source string) string {
+	if parses(source) || !parses(source+"\n;") {
+		return source
+	}
+	return source + "\n;"
+}
+
+// parses reports whether source scans and parses without errors.
+func parses(source string) bool {
+	scanner := lexer.NewScanner(strings.NewReader(source), io.Discard)
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors()) > 0 {
+		return false
+	}
+	_, hasError := ast.NewParser(tokens, io.Discard).Parse()
+	return !hasError
+}
+
+// isIncomplete reports whether source has more opening brackets than closing
+// ones, meaning the user is still typing a block, a call or a literal.
+func isIncomplete(source string) bool {
+	scanner := lexer.NewScanner(strings.NewReader(source), io.Discard)
+	tokens := scanner.ScanTokens()
+	for _, err := range scanner.Errors() {
+		if d, ok := err.(diagnostic.Diagnostic); ok && d.Code == diagnostic.CodeUnterminatedRawString {
+			return true
+		}
+	}
+
+	depth := 0
+	for _, token := range tokens {
+		switch token.TokenType {
+		case ast.TokenLeftBrace, ast.TokenLeftParenthesis, ast.TokenLeftBracket:
+			depth++
+		case ast.TokenRightBrace, ast.TokenRightParenthesis, ast.TokenRightBracket:
+			depth--
+		}
+	}
+	return depth > 0
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "muat.indos")
+	if err := os.WriteFile(script, []byte("misal dimuat = \"ya\";\ndimuat;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	in := strings.NewReader(strings.Join([]string{
+		`misal a = 1`,
+		`fungsi f(x) {`,
+		`    balikin x + a;`,
+		`}`,
+		`f(1)`,
+		``,
+		`1 - "a";`,
+		`:muat ` + script,
+		`dimuat`,
+		`:reset`,
+		`a`,
+		`:keluar`,
+		`cetak "tidak dijalankan";`,
+	}, "\n"))
+	out := new(strings.Builder)
+	errOut := new(strings.Builder)
+
+	if code := repl(in, out, errOut); code != 0 {
+		t.Fatalf("expected exit code 0 while actual is %d", code)
+	}
+
+	expectedOut := "indoscript REPL, type :keluar to exit\n" +
+		"> > ... ... > 2\n" +
+		"> > > > \"ya\"\n" +
+		"> > > "
+	if out.String() != expectedOut {
+		t.Fatalf("expected output %q while actual is %q", expectedOut, out.String())
+	}
+	for _, expected := range []string{"operands must be numbers", "Undefined variable a"} {
+		if !strings.Contains(errOut.String(), expected) {
+			t.Fatalf("expected %q in stdErr, got %q", expected, errOut.String())
+		}
+	}
+}
+
+func TestReplAddsSemicolon(t *testing.T) {
+	in := strings.NewReader("cetak 1 // komentar\nmisal m = {\"a\": 1}\nm\n")
+	out := new(strings.Builder)
+	errOut := new(strings.Builder)
+
+	if code := repl(in, out, errOut); code != 0 {
+		t.Fatalf("expected exit code 0 while actual is %d", code)
+	}
+
+	expectedOut := "indoscript REPL, type :keluar to exit\n> 1\n> > {\"a\": 1}\n> \n"
+	if out.String() != expectedOut {
+		t.Fatalf("expected output %q while actual is %q", expectedOut, out.String())
+	}
+	if errOut.Len() != 0 {
+		t.Fatalf("expected no error, got %q", errOut.String())
+	}
+}
+
+func TestReplReportsReadError(t *testing.T) {
+	in := strings.NewReader("cetak \"" + strings.Repeat("a", bufio.MaxScanTokenSize) + "\";\n")
+	out := new(strings.Builder)
+	errOut := new(strings.Builder)
+
+	if code := repl(in, out, errOut); code != exitIOErr {
+		t.Fatalf("expected exit code %d while actual is %d", exitIOErr, code)
+	}
+	if !strings.Contains(errOut.String(), "token too long") {
+		t.Fatalf("expected the read error in stdErr, got %q", errOut.String())
+	}
+}
+
+func TestWithSemicolon(t *testing.T) {
+	testcases := map[string]string{
+		"cetak 1;":                "cetak 1;",
+		"cetak 1":                 "cetak 1\n;",
+		"cetak 1 // komentar":     "cetak 1 // komentar\n;",
+		`misal m = {"a": 1}`:      "misal m = {\"a\": 1}\n;",
+		"fungsi f() {}":           "fungsi f() {}",
+		"jika benar { cetak 1; }": "jika benar { cetak 1; }",
+		"cetak 1 +":               "cetak 1 +",
+	}
+	for source, expected := range testcases {
+		if actual := withSemicolon(source); actual != expected {
+			t.Fatalf("expected withSemicolon(%q) to be %q while actual is %q", source, expected, actual)
+		}
+	}
+}
+
+func TestIsIncomplete(t *testing.T) {
+	testcases := map[string]bool{
+		"cetak 1;":            false,
+		"fungsi f() {":        true,
+		"fungsi f() {\n}":     false,
+		"misal xs = [1,":      true,
+		"f(1,\n2":             true,
+		"cetak \"{\";":        false,
+		"} {":                 false,
+		"jika a { jika b { }": true,
+		"cetak `baris":        true,
+		"cetak `{\n}`;":       false,
+	}
+	for source, expected := range testcases {
+		if actual := isIncomplete(source); actual != expected {
+			t.Fatalf("expected isIncomplete(%q) to be %v", source, expected)
+		}
+	}
+}